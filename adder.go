@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 )
@@ -43,15 +45,20 @@ func newAdder(cw *cloudwatch.CloudWatch, name, leftIn, rightIn, carryIn string)
 	return a
 }
 
-func (a *adder) build() error {
-	if err := a.ha1.build(); err != nil {
-		return err
-	}
-	if err := a.ha2.build(); err != nil {
-		return err
-	}
-	rule := fmt.Sprintf("ALARM(%q) OR ALARM(%q)", a.ha1.coutName(), a.ha2.coutName())
-	return pca(a.cw, a.coutName(), rule)
+func (a *adder) build(ctx context.Context, tx *buildTx) error {
+	return tx.build(ctx, a.alarms())
+}
+
+// alarms describes the alarms of the adder's half-adders and its carry
+// output alarm.
+func (a *adder) alarms() []alarmSpec {
+	specs := append(a.ha1.alarms(), a.ha2.alarms()...)
+	return append(specs, alarmSpec{
+		name:   a.coutName(),
+		rule:   fmt.Sprintf("ALARM(%q) OR ALARM(%q)", a.ha1.coutName(), a.ha2.coutName()),
+		inputs: []string{a.ha1.coutName(), a.ha2.coutName()},
+		role:   roleAdderCarry,
+	})
 }
 
 func (a *adder) ha1Name() string {
@@ -70,27 +77,40 @@ func (a *adder) soutName() string {
 	return a.ha2.soutName()
 }
 
-func (a *adder) setMainInputs(leftIn bool, rightIn bool) error {
-	err := sas(a.cw, a.leftIn, leftIn)
+func (a *adder) setMainInputs(ctx context.Context, leftIn bool, rightIn bool) error {
+	err := sas(ctx, a.cw, a.leftIn, leftIn)
 	if err != nil {
 		return err
 	}
-	return sas(a.cw, a.rightIn, rightIn)
+	return sas(ctx, a.cw, a.rightIn, rightIn)
 }
 
-func (a *adder) setInputs(leftIn bool, rightIn bool, carryIn bool) error {
-	err := a.setMainInputs(leftIn, rightIn)
+func (a *adder) setInputs(ctx context.Context, leftIn bool, rightIn bool, carryIn bool) error {
+	err := a.setMainInputs(ctx, leftIn, rightIn)
 	if err != nil {
 		return err
 	}
-	return sas(a.cw, a.carryIn, carryIn)
+	return sas(ctx, a.cw, a.carryIn, carryIn)
 }
 
-func (a *adder) readOutputs() (carry bool, sum bool, err error) {
-	states, err := describeStates(a.cw, []string{
+func (a *adder) outputNames() []string {
+	return []string{
 		a.coutName(),
 		a.soutName(),
-	})
+	}
+}
+
+func (a *adder) readOutputs(ctx context.Context) (carry bool, sum bool, err error) {
+	return a.decodeOutputs(describeStates(ctx, a.cw, a.outputNames()))
+}
+
+// waitForOutputs is like readOutputs, but waits for the outputs to settle
+// after the inputs were set at the given time.
+func (a *adder) waitForOutputs(ctx context.Context, since time.Time, opts waitOptions) (carry bool, sum bool, err error) {
+	return a.decodeOutputs(waitForOutputs(ctx, a.cw, a.outputNames(), since, opts))
+}
+
+func (a *adder) decodeOutputs(states []string, err error) (carry bool, sum bool, _ error) {
 	if err != nil {
 		return false, false, err
 	}
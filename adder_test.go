@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"testing"
@@ -14,24 +15,25 @@ func TestAdderTruthTable(t *testing.T) {
 	b := make([]byte, 16)
 	rand.Read(b)
 	suffix := fmt.Sprintf(":%x", b)
+	ctx := context.Background()
 	cw, err := defaultClient()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := pcab(cw, "left"+suffix, false); err != nil {
+	if err := pcab(ctx, cw, "left"+suffix, false); err != nil {
 		t.Fatal(err)
 	}
-	defer func() { _ = daRecursive(cw, "left"+suffix) }()
-	if err := pcab(cw, "right"+suffix, false); err != nil {
+	defer func() { _ = daRecursive(ctx, cw, "left"+suffix) }()
+	if err := pcab(ctx, cw, "right"+suffix, false); err != nil {
 		t.Fatal(err)
 	}
-	defer func() { _ = daRecursive(cw, "right"+suffix) }()
-	if err := pcab(cw, "carry-input"+suffix, false); err != nil {
+	defer func() { _ = daRecursive(ctx, cw, "right"+suffix) }()
+	if err := pcab(ctx, cw, "carry-input"+suffix, false); err != nil {
 		t.Fatal(err)
 	}
-	defer func() { _ = daRecursive(cw, "carry-input"+suffix) }()
+	defer func() { _ = daRecursive(ctx, cw, "carry-input"+suffix) }()
 	a := newAdder(cw, "test"+suffix, "left"+suffix, "right"+suffix, "carry-input"+suffix)
-	if err := a.build(); err != nil {
+	if err := a.build(ctx, newBuildTx(cw, "test"+suffix)); err != nil {
 		t.Fatal(err)
 	}
 	truthTable := [][5]bool{
@@ -46,12 +48,14 @@ func TestAdderTruthTable(t *testing.T) {
 	}
 	for _, test := range truthTable {
 		t.Run("", func(t *testing.T) {
-			err := a.setInputs(test[0], test[1], test[2])
+			since := time.Now()
+			err := a.setInputs(ctx, test[0], test[1], test[2])
 			if err != nil {
 				t.Fatal(err)
 			}
-			time.Sleep(evaluationLatency)
-			carry, sum, err := a.readOutputs()
+			opts := defaultWaitOptions
+			opts.want = encodeStates(test[3], test[4])
+			carry, sum, err := a.waitForOutputs(ctx, since, opts)
 			if err != nil {
 				t.Fatal(err)
 			}
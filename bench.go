@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// The outputs of the ripple-carry adder, as read by outputNames: the 8 sum
+// bits, from least significant, and the overflow bit.
+const rcaOutputs = 9
+
+// observation is the value of all outputs at some time after setting inputs.
+type observation struct {
+	at     time.Duration
+	values [rcaOutputs]bool
+}
+
+// settleTimes returns, for each output that had to change from prev to want,
+// the time of the first observation from which it had the wanted value for
+// good. Outputs that didn't have to change are not reported, as they would
+// only tell how quickly the first read came back.
+func settleTimes(prev, want [rcaOutputs]bool, observations []observation) map[int]time.Duration {
+	times := make(map[int]time.Duration)
+	for i := 0; i < rcaOutputs; i++ {
+		if prev[i] == want[i] {
+			continue
+		}
+		for j := len(observations) - 1; j >= 0 && observations[j].values[i] == want[i]; j-- {
+			times[i] = observations[j].at
+		}
+	}
+	return times
+}
+
+// held reports whether the last observations all have the wanted values, over
+// at least two reads and the hold period. In a ripple carry adder, an output
+// can show its final value, then change again as a carry arrives: a single
+// read of the wanted values doesn't mean the outputs have settled.
+func held(observations []observation, want [rcaOutputs]bool, hold time.Duration) bool {
+	n := len(observations)
+	if n < 2 || observations[n-1].values != want || observations[n-2].values != want {
+		return false
+	}
+	first := n - 1
+	for first > 0 && observations[first-1].values == want {
+		first--
+	}
+	return observations[n-1].at-observations[first].at >= hold
+}
+
+// benchOnce adds a and b, polling the outputs every poll interval until they
+// have held the expected values for the hold period, and returns the settle
+// times of the outputs that changed.
+func (rca *rippleCarryAdder) benchOnce(ctx context.Context, a, b uint8, poll, hold, timeout time.Duration) (map[int]time.Duration, error) {
+	read := func() (values [rcaOutputs]bool, err error) {
+		states, err := describeStates(ctx, rca.cw, rca.outputNames())
+		if err != nil {
+			return values, err
+		}
+		for i, s := range states {
+			values[i] = s == cloudwatch.StateValueAlarm
+		}
+		return values, nil
+	}
+	prev, err := read()
+	if err != nil {
+		return nil, err
+	}
+	var want [rcaOutputs]bool
+	sum := toRegister(a + b)
+	copy(want[:], sum[:])
+	want[8] = uint16(a)+uint16(b) > 255
+	if err := rca.setInputs(ctx, toRegister(a), toRegister(b)); err != nil {
+		return nil, err
+	}
+	// Measuring from the last input set, as the first ones are set while
+	// the others are, one call at a time.
+	since := time.Now()
+	var observations []observation
+	for {
+		values, err := read()
+		if err != nil {
+			return nil, err
+		}
+		at := time.Since(since)
+		observations = append(observations, observation{at: at, values: values})
+		if held(observations, want, hold) {
+			return settleTimes(prev, want, observations), nil
+		}
+		if timeout > 0 && at > timeout {
+			return nil, &inconsistentReadError{
+				reason: fmt.Sprintf("outputs for %d+%d did not settle within %v", a, b, timeout),
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
+
+// benchResults collects the settle times of each output over many runs.
+type benchResults struct {
+	runs    int
+	elapsed time.Duration
+	settle  [rcaOutputs][]time.Duration
+
+	// For each run, when the last output settled.
+	total []time.Duration
+}
+
+func (r *benchResults) record(times map[int]time.Duration) {
+	r.runs++
+	var last time.Duration
+	for i, t := range times {
+		r.settle[i] = append(r.settle[i], t)
+		if t > last {
+			last = t
+		}
+	}
+	if len(times) > 0 {
+		r.total = append(r.total, last)
+	}
+}
+
+// bench runs random additions and records how long the outputs take to
+// settle. Consecutive runs use different operands, so most runs make some
+// outputs change.
+func (rca *rippleCarryAdder) bench(ctx context.Context, rnd *rand.Rand, runs int, poll, hold, timeout time.Duration, progress func(run int, times map[int]time.Duration)) (r benchResults, err error) {
+	start := time.Now()
+	defer func() { r.elapsed = time.Since(start) }()
+	for run := 0; run < runs; run++ {
+		a, b := uint8(rnd.Intn(256)), uint8(rnd.Intn(256))
+		times, err := rca.benchOnce(ctx, a, b, poll, hold, timeout)
+		if err != nil {
+			return r, err
+		}
+		r.record(times)
+		if progress != nil {
+			progress(run, times)
+		}
+	}
+	return r, nil
+}
+
+// percentile returns the p-th percentile (0 < p <= 100) of sorted durations,
+// using the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted)) + 0.999999)
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+func sortedDurations(ds []time.Duration) []time.Duration {
+	sorted := append([]time.Duration(nil), ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
+
+// writeBenchReport writes the percentiles of the settle times of each output,
+// and a histogram of the times until all outputs settled.
+func writeBenchReport(w io.Writer, r benchResults, buckets int) error {
+	_, _ = fmt.Fprintf(w, "%d runs in %v.\n\n", r.runs, r.elapsed.Round(time.Second))
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintln(tw, "OUTPUT\tN\tP50\tP90\tP99\tMAX\t")
+	row := func(label string, ds []time.Duration) {
+		s := sortedDurations(ds)
+		if len(s) == 0 {
+			_, _ = fmt.Fprintf(tw, "%s\t0\t-\t-\t-\t-\t\n", label)
+			return
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%v\t%v\t%v\t%v\t\n", label, len(s),
+			percentile(s, 50).Round(time.Millisecond),
+			percentile(s, 90).Round(time.Millisecond),
+			percentile(s, 99).Round(time.Millisecond),
+			s[len(s)-1].Round(time.Millisecond))
+	}
+	for i := 0; i < 8; i++ {
+		row(fmt.Sprintf("sum%d", i), r.settle[i])
+	}
+	row("overflow", r.settle[8])
+	row("all", r.total)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	return writeHistogram(w, sortedDurations(r.total), buckets)
+}
+
+// writeHistogram writes a text histogram of the sorted durations, with the
+// given number of equally wide buckets.
+func writeHistogram(w io.Writer, sorted []time.Duration, buckets int) error {
+	if len(sorted) == 0 || buckets < 1 {
+		return nil
+	}
+	const maxBar = 40
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	width := (hi - lo) / time.Duration(buckets)
+	if width <= 0 {
+		width = 1
+		buckets = 1
+	}
+	counts := make([]int, buckets)
+	for _, d := range sorted {
+		i := int((d - lo) / width)
+		if i >= buckets {
+			i = buckets - 1
+		}
+		counts[i]++
+	}
+	most := 0
+	for _, c := range counts {
+		if c > most {
+			most = c
+		}
+	}
+	_, _ = fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	for i, c := range counts {
+		from := lo + time.Duration(i)*width
+		_, _ = fmt.Fprintf(tw, "%v\t%d\t%s\n", from.Round(time.Millisecond), c, strings.Repeat("#", (c*maxBar+most-1)/most))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSettleTimes(t *testing.T) {
+	var prev, want [rcaOutputs]bool
+	want[0] = true
+	want[1] = true
+	observe := func(at time.Duration, bits ...int) observation {
+		o := observation{at: at}
+		for _, b := range bits {
+			o.values[b] = true
+		}
+		return o
+	}
+	// Bit 1 glitches before settling; bit 2 glitches but doesn't have to
+	// change, so is not reported.
+	observations := []observation{
+		observe(100*time.Millisecond, 1),
+		observe(200*time.Millisecond, 0, 2),
+		observe(300*time.Millisecond, 0, 1),
+		observe(400*time.Millisecond, 0, 1),
+	}
+	got := settleTimes(prev, want, observations)
+	wantTimes := map[int]time.Duration{
+		0: 200 * time.Millisecond,
+		1: 300 * time.Millisecond,
+	}
+	if !reflect.DeepEqual(got, wantTimes) {
+		t.Errorf("got %v, want %v", got, wantTimes)
+	}
+}
+
+func TestHeld(t *testing.T) {
+	var want [rcaOutputs]bool
+	want[0] = true
+	observe := func(at time.Duration, bits ...int) observation {
+		o := observation{at: at}
+		for _, b := range bits {
+			o.values[b] = true
+		}
+		return o
+	}
+	hold := 200 * time.Millisecond
+	for _, test := range []struct {
+		name         string
+		observations []observation
+		want         bool
+	}{
+		{"none", nil, false},
+		{"single read", []observation{observe(100*time.Millisecond, 0)}, false},
+		{"within hold period", []observation{
+			observe(100*time.Millisecond, 0),
+			observe(200*time.Millisecond, 0),
+		}, false},
+		{"held", []observation{
+			observe(100*time.Millisecond, 0),
+			observe(200*time.Millisecond, 0),
+			observe(300*time.Millisecond, 0),
+		}, true},
+		{"glitched", []observation{
+			observe(100*time.Millisecond, 0),
+			observe(200*time.Millisecond, 0, 1),
+			observe(300*time.Millisecond, 0),
+			observe(400*time.Millisecond, 0),
+		}, false},
+		{"changed at last", []observation{
+			observe(100*time.Millisecond, 0),
+			observe(200*time.Millisecond, 0),
+			observe(300*time.Millisecond, 0),
+			observe(400 * time.Millisecond),
+		}, false},
+	} {
+		if got := held(test.observations, want, hold); got != test.want {
+			t.Errorf("%s: got %t, want %t", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var ds []time.Duration
+	for i := 1; i <= 10; i++ {
+		ds = append(ds, time.Duration(i)*time.Second)
+	}
+	for _, test := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{50, 5 * time.Second},
+		{90, 9 * time.Second},
+		{99, 10 * time.Second},
+		{100, 10 * time.Second},
+		{1, time.Second},
+	} {
+		if got := percentile(ds, test.p); got != test.want {
+			t.Errorf("p%v: got %v, want %v", test.p, got, test.want)
+		}
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("got %v for no durations, want 0", got)
+	}
+}
+
+func TestWriteHistogram(t *testing.T) {
+	ds := []time.Duration{
+		1 * time.Second,
+		1 * time.Second,
+		1500 * time.Millisecond,
+		3 * time.Second,
+	}
+	var b strings.Builder
+	if err := writeHistogram(&b, ds, 2); err != nil {
+		t.Fatal(err)
+	}
+	want := `
+1s 3 ########################################
+2s 1 ##############
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
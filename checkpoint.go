@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// checkpoint is an append-only file listing the names of the alarms a build
+// has put, one per line, so that an interrupted build can be resumed without
+// putting those alarms again. The names come from the same naming functions
+// the circuits use to build themselves (adderName, adderLeftInName, etc.),
+// so they identify progress without any further bookkeeping.
+type checkpoint struct {
+	path string
+	f    *os.File
+	done map[string]bool
+}
+
+// defaultCheckpointPath returns the path of the checkpoint file for the named
+// circuit, in the user's cache directory.
+func defaultCheckpointPath(circuit string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cac", circuit+".checkpoint"), nil
+}
+
+// openCheckpoint opens the checkpoint file at path, creating it if needed.
+// If resume is true, the names already in the file are loaded as done,
+// otherwise the file is truncated.
+func openCheckpoint(path string, resume bool) (*checkpoint, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	c := &checkpoint{
+		path: path,
+		done: make(map[string]bool),
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if resume {
+		f, err := os.Open(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			s := bufio.NewScanner(f)
+			for s.Scan() {
+				c.done[s.Text()] = true
+			}
+			_ = f.Close()
+			if err := s.Err(); err != nil {
+				return nil, fmt.Errorf("reading checkpoint %q: %w", path, err)
+			}
+		}
+	} else {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	c.f = f
+	return c, nil
+}
+
+// record appends name to the checkpoint file. The file is synced so that the
+// record survives the process being killed right after.
+func (c *checkpoint) record(name string) error {
+	if _, err := fmt.Fprintln(c.f, name); err != nil {
+		return fmt.Errorf("recording %q in checkpoint %q: %w", name, c.path, err)
+	}
+	c.done[name] = true
+	return c.f.Sync()
+}
+
+func (c *checkpoint) close() error {
+	return c.f.Close()
+}
+
+// remove closes and deletes the checkpoint file, which is what should happen
+// once the build completes or is rolled back.
+func (c *checkpoint) remove() error {
+	_ = c.f.Close()
+	return os.Remove(c.path)
+}
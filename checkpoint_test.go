@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "sub", "test.checkpoint")
+	c, err := openCheckpoint(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.done) != 0 {
+		t.Errorf("got %d names done in new checkpoint, want 0", len(c.done))
+	}
+	for _, name := range []string{"ground:rca:test", "lin0:rca:test"} {
+		if err := c.record(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := c.close(); err != nil {
+		t.Fatal(err)
+	}
+	c, err = openCheckpoint(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.done["ground:rca:test"] || !c.done["lin0:rca:test"] || len(c.done) != 2 {
+		t.Errorf("got %v after resuming, want the 2 recorded names", c.done)
+	}
+	if err := c.close(); err != nil {
+		t.Fatal(err)
+	}
+	c, err = openCheckpoint(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.done) != 0 {
+		t.Errorf("got %v after starting over, want no names", c.done)
+	}
+	if err := c.remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("got %v, want checkpoint file to be removed", err)
+	}
+}
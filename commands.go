@@ -0,0 +1,752 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// command is a subcommand of cac, e.g., build in "cac build".
+type command struct {
+	name string
+
+	// The arguments after the flags, for the usage message, e.g., "[a b]".
+	args string
+
+	summary string
+
+	// setup defines the flags of the command, and returns the function
+	// running it with the arguments left after the flags.
+	setup func(fs *flag.FlagSet) func(ctx context.Context, args []string) error
+}
+
+var commands = []*command{
+	{name: "build", summary: "build the circuit, putting all of its alarms", setup: buildCommand},
+	{name: "add", args: "[a b]", summary: "add a and b on the circuit, or random operands, or those in a file", setup: addCommand},
+	{name: "verify", summary: "check all the 65,536 additions on the circuit, or a sample of them", setup: verifyCommand},
+	{name: "bench", summary: "measure how long each output bit takes to settle over random additions", setup: benchCommand},
+	{name: "timeline", summary: "print the state transitions of the alarms of the circuit, from their history", setup: timelineCommand},
+	{name: "graph", summary: "print a graph of the circuit, as modeled or as deployed", setup: graphCommand},
+	{name: "dashboard", summary: "print or put a CloudWatch dashboard showing the states of the alarms of the circuit", setup: dashboardCommand},
+	{name: "ui", summary: "serve a web dashboard to set the inputs of the circuit and watch its alarms", setup: uiCommand},
+	{name: "tui", summary: "operate the circuit interactively from the terminal", setup: tuiCommand},
+	{name: "rm", summary: "remove the circuit, deleting all of its alarms", setup: rmCommand},
+	{name: "list", summary: "list the circuits in the account and region, found by the tags of their alarms", setup: listCommand},
+	{name: "gc", summary: "delete the alarms of cac not reachable from any complete circuit", setup: gcCommand},
+	{name: "regions", summary: "list the regions where CloudWatch is available", setup: regionsCommand},
+}
+
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// flagSet returns the flag set of the command, with the flags common to all
+// commands, the function running the command, and the overall deadline the
+// flags set.
+func (c *command) flagSet() (*flag.FlagSet, func(context.Context, []string) error, *time.Duration) {
+	fs := flag.NewFlagSet("cac "+c.name, flag.ExitOnError)
+	run := c.setup(fs)
+	fs.StringVar(&profile, "profile", cfg.profile, "the AWS profile to use for credentials")
+	fs.StringVar(&region, "region", cfg.region, "the AWS region to create/use alarms in")
+	fs.StringVar(&endpoint, "endpoint", cfg.endpoint, "the custom `endpoint` if you need to override the default")
+	fs.StringVar(&headers, "headers", "", "additional `headers` if required, in the form k1=v1,k2=v2")
+	fs.BoolVar(&verbose, "verbose", false, "log diagnostic messages")
+	timeout := fs.Duration("timeout", 0, "the overall `deadline` for all operations (0 for none)")
+	fs.DurationVar(&callTimeout, "call-timeout", 30*time.Second, "the `deadline` for each API call (0 for none)")
+	fs.StringVar(&outputFormat, "output", "text", "the `format` of the results: text, or json for scripts")
+	fs.Usage = func() {
+		w := fs.Output()
+		usage := "cac " + c.name + " [flags]"
+		if c.args != "" {
+			usage += " " + c.args
+		}
+		_, _ = fmt.Fprintf(w, "Usage: %s\n\n%s%s.\n\nFlags:\n", usage, strings.ToUpper(c.summary[:1]), c.summary[1:])
+		fs.PrintDefaults()
+	}
+	return fs, run, timeout
+}
+
+// usage writes the list of commands.
+func usage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage: cac <command> [flags] [arguments]")
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		_, _ = fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, `Run "cac help <command>" for the flags of a command.`)
+	if path, err := defaultConfigPath(); err == nil {
+		_, _ = fmt.Fprintf(w, "Flag defaults are read from %s, if it exists.\n", path)
+	}
+}
+
+// usageError is an error in the arguments of a command.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+func noArgs(args []string) error {
+	if len(args) > 0 {
+		return usageError(fmt.Sprintf("unexpected arguments %s", strings.Join(quoteAll(args), " ")))
+	}
+	return nil
+}
+
+// circuitFlag defines the flag for the name of the circuit a command
+// operates on.
+func circuitFlag(fs *flag.FlagSet) *string {
+	return fs.String("name", cfg.circuit, "the `name` of the circuit")
+}
+
+// waitFlags defines the flags for waiting for outputs to settle.
+func waitFlags(fs *flag.FlagSet) *waitOptions {
+	opts := defaultWaitOptions
+	fs.DurationVar(&opts.timeout, "wait-timeout", opts.timeout, "how long to wait at most for outputs to settle after setting inputs")
+	fs.DurationVar(&opts.quiet, "quiet", opts.quiet, "how long outputs must be seen not to change to be deemed settled, when they are not those expected")
+	return &opts
+}
+
+func workersFlag(fs *flag.FlagSet) *int {
+	return fs.Int("workers", 4, "the maximum `number` of concurrent API calls")
+}
+
+func newCircuit(name string) (*rippleCarryAdder, error) {
+	cw, err := defaultClient()
+	if err != nil {
+		return nil, err
+	}
+	return newRippleCarryAdder(cw, name), nil
+}
+
+func buildCommand(fs *flag.FlagSet) func(context.Context, []string) error {
+	name := circuitFlag(fs)
+	rollback := fs.Bool("rollback", true, "delete the alarms created by a build that fails or is interrupted, including those put by resumed builds, but not those that existed before")
+	resume := fs.Bool("resume", false, "resume an interrupted build, skipping the alarms recorded in the checkpoint file")
+	checkpointPath := fs.String("checkpoint", "", "the checkpoint `file` (defaults to one per circuit in the user's cache directory)")
+	workers := workersFlag(fs)
+	rate := fs.Float64("rate", 3, "the maximum `rate` of PutCompositeAlarm calls per second (0 for no limit)")
+	dryRun := fs.Bool("dry-run", false, "only print the alarms to put, layer by layer")
+	return func(ctx context.Context, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		if *dryRun {
+			return printBuildPlan(newRippleCarryAdder(nil, *name), nil, nil)
+		}
+		rca, err := newCircuit(*name)
+		if err != nil {
+			return err
+		}
+		if *checkpointPath == "" {
+			*checkpointPath, err = defaultCheckpointPath(*name)
+			if err != nil {
+				return err
+			}
+		}
+		cp, err := openCheckpoint(*checkpointPath, *resume)
+		if err != nil {
+			return err
+		}
+		if *resume {
+			log.Printf("Resuming build, %d alarms already put.", len(cp.done))
+		}
+		tx := newBuildTx(rca.cw, *name)
+		tx.checkpoint = cp
+		tx.workers = *workers
+		tx.limiter = newTokenBucket(*rate, *workers)
+		err = rca.build(ctx, tx)
+		if err != nil {
+			if *rollback {
+				log.Printf("Build failed, deleting the %d alarms it created.", len(tx.created()))
+				// Not using ctx, which may be what made the build fail.
+				if rerr := tx.rollback(context.Background()); rerr != nil {
+					log.Printf("Could not roll back: %v", rerr)
+					_ = cp.close()
+				} else {
+					_ = cp.remove()
+					err = &rolledBackError{err: err}
+				}
+			} else {
+				log.Printf("Build failed, keeping the %d alarms put so far; use cac build -resume to continue.", len(tx.put))
+				_ = cp.close()
+			}
+			if jsonOutput() {
+				if perr := printBuildPlan(rca, tx.put, err); perr != nil {
+					log.Print(perr)
+				}
+			}
+			return err
+		}
+		if err := cp.remove(); err != nil {
+			log.Print(err)
+		}
+		if jsonOutput() {
+			return printBuildPlan(rca, tx.put, nil)
+		}
+		return nil
+	}
+}
+
+// printBuildPlan prints the alarms of the circuit, layer by layer in the
+// order a build puts them, and those put, if any.
+func printBuildPlan(rca *rippleCarryAdder, put []string, buildErr error) error {
+	layers, err := buildLayers(rca.alarms())
+	if err != nil {
+		return err
+	}
+	if jsonOutput() {
+		return printJSON(newBuildJSON(rca.name, layers, put, buildErr))
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "LAYER\tALARM\tRULE")
+	for i, layer := range layers {
+		for _, s := range layer {
+			_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\n", i, s.name, s.rule)
+		}
+	}
+	return tw.Flush()
+}
+
+func addCommand(fs *flag.FlagSet) func(context.Context, []string) error {
+	name := circuitFlag(fs)
+	operandsPath := fs.String("operands", "", "a `file` of pairs of operands to add, one pair per line, e.g., 17 250")
+	count := fs.Int("count", 1, "the `number` of random additions, without operands")
+	seed := fs.Int64("seed", time.Now().Unix(), "seed for random operands - only for reproducibility")
+	timeline := fs.Bool("timeline", false, "print the state transitions of the alarms since the last inputs were set")
+	vcdPath := fs.String("vcd", "", "with -timeline, also write the transitions as a Value Change Dump to `file`")
+	workers := workersFlag(fs)
+	waitOpts := waitFlags(fs)
+	return func(ctx context.Context, args []string) error {
+		var pairs []operands
+		switch {
+		case len(args) == 2:
+			a, err := parseOperand(args[0])
+			if err != nil {
+				return usageError(err.Error())
+			}
+			b, err := parseOperand(args[1])
+			if err != nil {
+				return usageError(err.Error())
+			}
+			pairs = append(pairs, operands{a: a, b: b})
+		case len(args) != 0:
+			return usageError("want 2 operands, or none")
+		case *operandsPath != "":
+			f, err := os.Open(*operandsPath)
+			if err != nil {
+				return err
+			}
+			pairs, err = parseOperands(f)
+			_ = f.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %w", *operandsPath, err)
+			}
+		default:
+			log.Printf("Using seed %d.", *seed)
+			rand.Seed(*seed)
+			for i := 0; i < *count; i++ {
+				pairs = append(pairs, operands{
+					a: uint8(rand.Intn(256)),
+					b: uint8(rand.Intn(256)),
+				})
+			}
+		}
+		rca, err := newCircuit(*name)
+		if err != nil {
+			return err
+		}
+		// When the inputs were last set, as the start of the timeline.
+		var lastInputs time.Time
+		var results []exerciseResult
+		for _, p := range pairs {
+			lastInputs = time.Now()
+			var r exerciseResult
+			if r, err = rca.add(ctx, p.a, p.b, *waitOpts); err != nil {
+				break
+			}
+			results = append(results, r)
+			if !jsonOutput() {
+				logAddition(r, *timeline)
+			}
+		}
+		var transitions []transition
+		if *timeline && err == nil {
+			transitions, err = timelineSince(ctx, rca, lastInputs, *vcdPath, *workers)
+		}
+		if jsonOutput() {
+			// Even on failure, with the results so far.
+			out := struct {
+				Results  []additionJSON   `json:"results"`
+				Summary  summaryJSON      `json:"summary"`
+				Timeline []transitionJSON `json:"timeline,omitempty"`
+				Error    string           `json:"error,omitempty"`
+			}{
+				Results: []additionJSON{},
+				Summary: newSummaryJSON(summarize(results)),
+				Error:   errorJSON(err),
+			}
+			for _, r := range results {
+				out.Results = append(out.Results, newAdditionJSON(r))
+			}
+			if *timeline && err == nil {
+				out.Timeline = newTimelineJSON(transitions, lastInputs)
+			}
+			if perr := printJSON(out); perr != nil {
+				return perr
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		if len(results) > 1 {
+			log.Printf("SUMMARY: %v.", summarize(results))
+		}
+		if *timeline {
+			return rca.writeTimeline(os.Stdout, transitions, lastInputs)
+		}
+		return nil
+	}
+}
+
+// logAddition logs the operands and outputs of an addition, for people.
+func logAddition(r exerciseResult, timeline bool) {
+	log.Printf("Added %d and %d, want %d", r.a, r.b, r.a+r.b)
+	log.Printf("  a = %s (%d)", toRegister(r.a), r.a)
+	log.Printf("  b = %s (%d)", toRegister(r.b), r.b)
+	if r.overflow {
+		log.Printf("WARNING: The computation overflowed.")
+	}
+	sum := fromRegister(r.sum)
+	log.Printf("sum = %s (%d)", r.sum, sum)
+	if r.ok {
+		log.Printf("STATUS: Success after %v.", r.latency)
+	} else {
+		log.Printf("STATUS: Failed!!! Because %d != %d.", sum, r.a+r.b)
+		if !timeline {
+			log.Print("Run with -timeline to see how the outputs changed.")
+		}
+	}
+}
+
+// timelineSince returns the state transitions of the alarms of the circuit
+// since origin, and writes them to a Value Change Dump file, unless vcdPath
+// is empty.
+func timelineSince(ctx context.Context, rca *rippleCarryAdder, origin time.Time, vcdPath string, workers int) ([]transition, error) {
+	// Allow for some clock skew.
+	since := origin.Add(-maxClockSkew)
+	transitions, err := rca.timeline(ctx, since, time.Now(), workers)
+	if err != nil {
+		return nil, err
+	}
+	if vcdPath == "" {
+		return transitions, nil
+	}
+	alarms := rca.alarmNames()
+	current, err := describeStates(ctx, rca.cw, alarms)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(vcdPath)
+	if err != nil {
+		return nil, err
+	}
+	err = writeVCD(f, alarms, initialStates(alarms, current, transitions, since), transitions, since)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return transitions, err
+}
+
+func verifyCommand(fs *flag.FlagSet) func(context.Context, []string) error {
+	name := circuitFlag(fs)
+	stride := fs.Int("stride", 1, "check one every `n` pairs of operands")
+	limit := fs.Int("limit", 0, "the maximum `number` of additions to check (0 for no limit)")
+	reportPath := fs.String("report", "", "the `file` to write the report to (defaults to standard output)")
+	waitOpts := waitFlags(fs)
+	return func(ctx context.Context, args []string) (err error) {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		rca, err := newCircuit(*name)
+		if err != nil {
+			return err
+		}
+		report := os.Stdout
+		if *reportPath != "" {
+			report, err = os.Create(*reportPath)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if cerr := report.Close(); err == nil {
+					err = cerr
+				}
+			}()
+		}
+		v, verr := rca.verifyExhaustive(ctx, *waitOpts, *stride, *limit, func(v *verification) {
+			if v.checked%256 == 0 && !jsonOutput() {
+				log.Printf("Checked %d additions, %d mismatches.", v.checked, len(v.mismatches))
+			}
+		})
+		// Write the report even if the verification stopped early.
+		if jsonOutput() {
+			err = encodeJSON(report, newVerificationJSON(v, verr))
+		} else {
+			err = writeReport(report, v)
+		}
+		if err != nil {
+			return err
+		}
+		if verr != nil {
+			return verr
+		}
+		if len(v.mismatches) > 0 {
+			return fmt.Errorf("%d of %d additions were wrong", len(v.mismatches), v.checked)
+		}
+		return nil
+	}
+}
+
+func benchCommand(fs *flag.FlagSet) func(context.Context, []string) error {
+	name := circuitFlag(fs)
+	runs := fs.Int("runs", 20, "the `number` of additions")
+	poll := fs.Duration("poll", 100*time.Millisecond, "the `interval` between reads of the outputs")
+	hold := fs.Duration("hold", 5*time.Second, "how long outputs must keep the expected values to be deemed settled")
+	buckets := fs.Int("buckets", 10, "the `number` of buckets of the histogram")
+	seed := fs.Int64("seed", time.Now().Unix(), "seed for random operands - only for reproducibility")
+	waitTimeout := fs.Duration("wait-timeout", defaultWaitOptions.timeout, "how long to wait at most for outputs to settle after setting inputs")
+	return func(ctx context.Context, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		rca, err := newCircuit(*name)
+		if err != nil {
+			return err
+		}
+		log.Printf("Using seed %d.", *seed)
+		rnd := rand.New(rand.NewSource(*seed))
+		results, err := rca.bench(ctx, rnd, *runs, *poll, *hold, *waitTimeout, func(run int, times map[int]time.Duration) {
+			if verbose {
+				log.Printf("Run %d: %d outputs changed.", run+1, len(times))
+			}
+		})
+		var werr error
+		if jsonOutput() {
+			werr = printJSON(newBenchJSON(results, err))
+		} else {
+			werr = writeBenchReport(os.Stdout, results, *buckets)
+		}
+		if werr != nil {
+			return werr
+		}
+		return err
+	}
+}
+
+func timelineCommand(fs *flag.FlagSet) func(context.Context, []string) error {
+	name := circuitFlag(fs)
+	since := fs.Duration("since", 10*time.Minute, "how far back to go")
+	vcdPath := fs.String("vcd", "", "also write the transitions as a Value Change Dump to `file`, e.g., for GTKWave")
+	workers := workersFlag(fs)
+	return func(ctx context.Context, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		rca, err := newCircuit(*name)
+		if err != nil {
+			return err
+		}
+		origin := time.Now().Add(-*since)
+		transitions, err := timelineSince(ctx, rca, origin, *vcdPath, *workers)
+		if err != nil {
+			return err
+		}
+		if jsonOutput() {
+			return printJSON(newTimelineJSON(transitions, origin))
+		}
+		return rca.writeTimeline(os.Stdout, transitions, origin)
+	}
+}
+
+func graphCommand(fs *flag.FlagSet) func(context.Context, []string) error {
+	name := circuitFlag(fs)
+	live := fs.Bool("live", false, "discover the graph from the deployed alarms, colored by state, instead of the circuit model")
+	format := fs.String("format", "dot", "the `format` of the graph: "+strings.Join(graphFormatNames(), ", ")+" (json with -output json)")
+	return func(ctx context.Context, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		if jsonOutput() {
+			formatSet := false
+			fs.Visit(func(f *flag.Flag) {
+				formatSet = formatSet || f.Name == "format"
+			})
+			if formatSet && *format != "json" {
+				return usageError(fmt.Sprintf("-format %s conflicts with -output json", *format))
+			}
+			*format = "json"
+		}
+		if _, ok := graphFormats[*format]; !ok {
+			return usageError(fmt.Sprintf("unknown format %q", *format))
+		}
+		rca := newRippleCarryAdder(nil, *name)
+		if *live {
+			var err error
+			if rca, err = newCircuit(*name); err != nil {
+				return err
+			}
+		}
+		return rca.saveGraph(ctx, os.Stdout, *live, *format)
+	}
+}
+
+func dashboardCommand(fs *flag.FlagSet) func(context.Context, []string) error {
+	name := circuitFlag(fs)
+	put := fs.String("put", "", "put the dashboard with the given `name` instead of printing it")
+	return func(ctx context.Context, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		rca, err := newCircuit(*name)
+		if err != nil {
+			return err
+		}
+		body, err := rca.dashboard(ctx, *put)
+		if err != nil {
+			return err
+		}
+		switch {
+		case *put == "":
+			fmt.Printf("%s\n", body)
+		case jsonOutput():
+			return printJSON(struct {
+				Dashboard string `json:"dashboard"`
+			}{*put})
+		}
+		return nil
+	}
+}
+
+func uiCommand(fs *flag.FlagSet) func(context.Context, []string) error {
+	name := circuitFlag(fs)
+	addr := fs.String("addr", "localhost:8080", "the `address` to listen on")
+	refresh := fs.Duration("refresh", time.Second, "the `interval` between reads of the alarm states")
+	return func(ctx context.Context, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		rca, err := newCircuit(*name)
+		if err != nil {
+			return err
+		}
+		return rca.serveDashboard(ctx, *addr, *refresh)
+	}
+}
+
+func tuiCommand(fs *flag.FlagSet) func(context.Context, []string) error {
+	name := circuitFlag(fs)
+	refresh := fs.Duration("refresh", time.Second, "the `interval` between reads of the outputs")
+	return func(ctx context.Context, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		rca, err := newCircuit(*name)
+		if err != nil {
+			return err
+		}
+		return rca.runTUI(ctx, *refresh)
+	}
+}
+
+func rmCommand(fs *flag.FlagSet) func(context.Context, []string) error {
+	name := circuitFlag(fs)
+	by := fs.String("by", "scheme", "how to find the alarms to remove: by the circuit's naming `scheme`, by tag, or by name pattern (scheme, tag, or pattern)")
+	pattern := fs.String("pattern", "", "the name `regexp` of the alarms to remove with -by pattern (defaults to the circuit's suffix)")
+	workers := workersFlag(fs)
+	return func(ctx context.Context, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		if *by != "tag" && *by != "pattern" && *by != "scheme" {
+			return usageError(fmt.Sprintf("unknown -by %q", *by))
+		}
+		rca, err := newCircuit(*name)
+		if err != nil {
+			return err
+		}
+		var names []string
+		switch *by {
+		case "tag":
+			tagging, err := defaultTaggingClient()
+			if err != nil {
+				return err
+			}
+			byCircuit, err := taggedAlarms(ctx, tagging, *name)
+			if err != nil {
+				return err
+			}
+			names = byCircuit[*name]
+		case "pattern":
+			if *pattern == "" {
+				*pattern = ":rca:" + regexp.QuoteMeta(*name) + "$"
+			}
+			re, err := regexp.Compile(*pattern)
+			if err != nil {
+				return usageError(err.Error())
+			}
+			names, err = alarmsMatching(ctx, rca.cw, re)
+			if err != nil {
+				return err
+			}
+		case "scheme":
+			rules, err := describeRules(ctx, rca.cw, rca.alarmNames())
+			if err != nil {
+				return err
+			}
+			for _, name := range rca.alarmNames() {
+				if _, ok := rules[name]; ok {
+					names = append(names, name)
+				}
+			}
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("found no alarms of circuit %q to remove by %s", *name, *by)
+		}
+		log.Printf("Removing %d alarms.", len(names))
+		if err := removeAlarms(ctx, rca.cw, names, *workers); err != nil {
+			return err
+		}
+		if jsonOutput() {
+			return printJSON(struct {
+				Removed []string `json:"removed"`
+			}{append([]string{}, names...)})
+		}
+		return nil
+	}
+}
+
+func listCommand(fs *flag.FlagSet) func(context.Context, []string) error {
+	return func(ctx context.Context, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		cw, err := defaultClient()
+		if err != nil {
+			return err
+		}
+		tagging, err := defaultTaggingClient()
+		if err != nil {
+			return err
+		}
+		summaries, err := listCircuits(ctx, cw, tagging)
+		if err != nil {
+			return err
+		}
+		if jsonOutput() {
+			return printJSON(newCircuitsJSON(summaries))
+		}
+		return printCircuits(os.Stdout, summaries)
+	}
+}
+
+func gcCommand(fs *flag.FlagSet) func(context.Context, []string) error {
+	dryRun := fs.Bool("dry-run", false, "only list the alarms that would be deleted")
+	yes := fs.Bool("yes", false, "delete without asking for confirmation")
+	minAge := fs.Duration("min-age", time.Hour, "keep incomplete circuits with alarms configured more recently than this `duration`, as they may be being built")
+	workers := workersFlag(fs)
+	return func(ctx context.Context, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		cw, err := defaultClient()
+		if err != nil {
+			return err
+		}
+		rules, configured, err := allRules(ctx, cw)
+		if err != nil {
+			return err
+		}
+		tagging, err := defaultTaggingClient()
+		if err != nil {
+			return err
+		}
+		tagged, err := taggedAlarms(ctx, tagging, "")
+		if err != nil {
+			return err
+		}
+		since := time.Now().Add(-*minAge)
+		names := orphans(rules, tagged, func(circuit string) bool {
+			if buildInProgress(circuit, tagged[circuit], configured, since) {
+				log.Printf("Keeping circuit %q, which may be being built.", circuit)
+				return true
+			}
+			return false
+		})
+		if !jsonOutput() {
+			for _, n := range names {
+				fmt.Println(n)
+			}
+		}
+		deleted := false
+		if len(names) == 0 {
+			log.Print("No orphaned alarms found.")
+		} else if !*dryRun && (*yes || confirm(os.Stdin, os.Stderr, fmt.Sprintf("Delete these %d alarms?", len(names)))) {
+			if err := removeAlarms(ctx, cw, names, *workers); err != nil {
+				return err
+			}
+			deleted = true
+			log.Printf("Deleted %d alarms.", len(names))
+		}
+		if jsonOutput() {
+			return printJSON(struct {
+				Orphans []string `json:"orphans"`
+				Deleted bool     `json:"deleted"`
+			}{append([]string{}, names...), deleted})
+		}
+		return nil
+	}
+}
+
+func regionsCommand(fs *flag.FlagSet) func(context.Context, []string) error {
+	return func(ctx context.Context, args []string) error {
+		if err := noArgs(args); err != nil {
+			return err
+		}
+		var regions []string
+		for _, r := range endpoints.AwsPartition().Services()[cloudwatch.EndpointsID].Regions() {
+			regions = append(regions, r.ID())
+		}
+		for _, r := range endpoints.AwsCnPartition().Services()[cloudwatch.EndpointsID].Regions() {
+			regions = append(regions, r.ID())
+		}
+		sort.Strings(regions)
+		if jsonOutput() {
+			return printJSON(regions)
+		}
+		for _, r := range regions {
+			fmt.Println(r)
+		}
+		return nil
+	}
+}
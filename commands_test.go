@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// keepGlobalFlags restores the flags common to all commands, which tests
+// share, after the test defines them again.
+func keepGlobalFlags(t *testing.T) {
+	p, r, e, h, v, c, o := profile, region, endpoint, headers, verbose, callTimeout, outputFormat
+	t.Cleanup(func() {
+		profile, region, endpoint, headers, verbose, callTimeout, outputFormat = p, r, e, h, v, c, o
+	})
+}
+
+func TestCommandFlagSets(t *testing.T) {
+	keepGlobalFlags(t)
+	for _, c := range commands {
+		// Defining the same flag twice panics.
+		fs, run, timeout := c.flagSet()
+		if run == nil || timeout == nil {
+			t.Errorf("%s: no function or timeout", c.name)
+		}
+		if fs.Lookup("profile") == nil {
+			t.Errorf("%s: no -profile flag", c.name)
+		}
+		if findCommand(c.name) != c {
+			t.Errorf("%s: not found by name", c.name)
+		}
+	}
+	if findCommand("other") != nil {
+		t.Error("found an unknown command")
+	}
+}
+
+func TestUsage(t *testing.T) {
+	var b strings.Builder
+	usage(&b)
+	for _, c := range commands {
+		if !strings.Contains(b.String(), "\n  "+c.name+" ") {
+			t.Errorf("usage lacks %s:\n%s", c.name, b.String())
+		}
+	}
+}
+
+func TestCommandArgs(t *testing.T) {
+	keepGlobalFlags(t)
+	for _, test := range []struct {
+		args []string
+	}{
+		{[]string{"add", "1"}},
+		{[]string{"add", "1", "256"}},
+		{[]string{"add", "1", "2", "3"}},
+		{[]string{"build", "extra"}},
+		{[]string{"graph", "-format", "png"}},
+		{[]string{"graph", "-output", "json", "-format", "svg"}},
+		{[]string{"rm", "-by", "other"}},
+	} {
+		fs, run, _ := findCommand(test.args[0]).flagSet()
+		if err := fs.Parse(test.args[1:]); err != nil {
+			t.Fatal(err)
+		}
+		var ue usageError
+		if err := run(context.Background(), fs.Args()); !errors.As(err, &ue) {
+			t.Errorf("%q: got %v, want a usage error", test.args, err)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// config holds the defaults of the flags common to all commands, read from
+// the configuration file. Flags override them.
+type config struct {
+	profile  string
+	region   string
+	endpoint string
+
+	// The name of the circuit commands operate on by default.
+	circuit string
+
+	// Sent with every request, on top of those of the -headers flag.
+	headers map[string]string
+
+	circuits map[string]circuitConfig
+}
+
+// circuitConfig describes a named circuit. Its profile, region and endpoint,
+// if set, override the top-level ones when the circuit is selected with
+// -name.
+type circuitConfig struct {
+	kind     string
+	width    int
+	profile  string
+	region   string
+	endpoint string
+}
+
+var defaultConfig = config{
+	profile: "computer",
+	region:  "eu-west-1",
+	circuit: "computer",
+}
+
+// cfg is the configuration in effect, loaded by main before defining flags.
+var cfg = defaultConfig
+
+// defaultConfigPath returns the path of the configuration file, which the
+// CAC_CONFIG environment variable overrides.
+func defaultConfigPath() (string, error) {
+	if path := os.Getenv("CAC_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cac", "config.toml"), nil
+}
+
+// loadConfig reads the configuration file, if any, on top of the defaults.
+func loadConfig(path string) (config, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return defaultConfig, nil
+	}
+	if err != nil {
+		return config{}, err
+	}
+	defer f.Close()
+	c, err := parseConfig(f)
+	if err != nil {
+		return config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// parseConfig parses a configuration like the following, where all keys are
+// optional:
+//
+//	profile = "computer"
+//	region = "eu-west-1"
+//	endpoint = ""
+//	circuit = "computer"
+//
+//	[headers]
+//	X-Debug = "1"
+//
+//	[circuits.computer]
+//	type = "rca"
+//	width = 8
+//	region = "us-east-1"
+func parseConfig(r io.Reader) (config, error) {
+	c := defaultConfig
+	tables, err := parseTOML(r)
+	if err != nil {
+		return config{}, err
+	}
+	for table, values := range tables {
+		switch {
+		case table == "":
+			for key, v := range values {
+				var dst *string
+				switch key {
+				case "profile":
+					dst = &c.profile
+				case "region":
+					dst = &c.region
+				case "endpoint":
+					dst = &c.endpoint
+				case "circuit":
+					dst = &c.circuit
+				default:
+					return config{}, fmt.Errorf("unknown key %q", key)
+				}
+				if err := v.as(key, dst); err != nil {
+					return config{}, err
+				}
+			}
+		case table == "headers":
+			c.headers = make(map[string]string)
+			for key, v := range values {
+				var s string
+				if err := v.as("headers."+key, &s); err != nil {
+					return config{}, err
+				}
+				c.headers[key] = s
+			}
+		case strings.HasPrefix(table, "circuits."):
+			name := strings.TrimPrefix(table, "circuits.")
+			cc, err := parseCircuitConfig(name, values)
+			if err != nil {
+				return config{}, err
+			}
+			if c.circuits == nil {
+				c.circuits = make(map[string]circuitConfig)
+			}
+			c.circuits[name] = cc
+		default:
+			return config{}, fmt.Errorf("unknown table [%s]", table)
+		}
+	}
+	return c, nil
+}
+
+func parseCircuitConfig(name string, values map[string]tomlValue) (circuitConfig, error) {
+	cc := circuitConfig{kind: "rca", width: 8}
+	for key, v := range values {
+		qualified := "circuits." + name + "." + key
+		var err error
+		switch key {
+		case "type":
+			err = v.as(qualified, &cc.kind)
+		case "width":
+			err = v.as(qualified, &cc.width)
+		case "profile":
+			err = v.as(qualified, &cc.profile)
+		case "region":
+			err = v.as(qualified, &cc.region)
+		case "endpoint":
+			err = v.as(qualified, &cc.endpoint)
+		default:
+			err = fmt.Errorf("unknown key %q", qualified)
+		}
+		if err != nil {
+			return cc, err
+		}
+	}
+	// The ripple carry adder is the only circuit, and its width is fixed.
+	if cc.kind != "rca" || cc.width != 8 {
+		return cc, fmt.Errorf("circuit %q: only 8 bit ripple carry adders (type rca, width 8) are supported, not type %s, width %d", name, cc.kind, cc.width)
+	}
+	return cc, nil
+}
+
+// applyCircuit sets the profile, region and endpoint of the circuit selected
+// with the -name flag, if configured, unless given as flags.
+func (c *config) applyCircuit(fs *flag.FlagSet) {
+	f := fs.Lookup("name")
+	if f == nil {
+		return
+	}
+	cc, ok := c.circuits[f.Value.String()]
+	if !ok {
+		return
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for _, o := range []struct {
+		flag  string
+		value string
+		dst   *string
+	}{
+		{"profile", cc.profile, &profile},
+		{"region", cc.region, &region},
+		{"endpoint", cc.endpoint, &endpoint},
+	} {
+		if o.value != "" && !set[o.flag] {
+			*o.dst = o.value
+		}
+	}
+}
+
+// tomlValue is a string, an integer or a boolean.
+type tomlValue struct {
+	v interface{}
+}
+
+// as stores the value in dst, a pointer to a string, int or bool, if the
+// types match.
+func (v tomlValue) as(key string, dst interface{}) error {
+	ok := false
+	switch d := dst.(type) {
+	case *string:
+		*d, ok = v.v.(string)
+	case *int:
+		var n int64
+		n, ok = v.v.(int64)
+		*d = int(n)
+	case *bool:
+		*d, ok = v.v.(bool)
+	}
+	if !ok {
+		return fmt.Errorf("key %q has a value of the wrong type", key)
+	}
+	return nil
+}
+
+// parseTOML parses the subset of TOML the configuration needs: tables, and
+// keys with string, integer or boolean values, one per line. It returns the
+// values by table, and by key, with the top-level table named "".
+func parseTOML(r io.Reader) (map[string]map[string]tomlValue, error) {
+	tables := map[string]map[string]tomlValue{"": {}}
+	table := ""
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(stripComment(s.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: malformed table header %q", n, line)
+			}
+			table = strings.TrimSpace(line[1 : len(line)-1])
+			if _, ok := tables[table]; ok {
+				return nil, fmt.Errorf("line %d: table [%s] defined twice", n, table)
+			}
+			tables[table] = make(map[string]tomlValue)
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: want key = value, got %q", n, line)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if unquoted, err := strconv.Unquote(key); err == nil {
+			key = unquoted
+		}
+		if key == "" {
+			return nil, fmt.Errorf("line %d: empty key", n)
+		}
+		if _, ok := tables[table][key]; ok {
+			return nil, fmt.Errorf("line %d: key %q defined twice", n, key)
+		}
+		v, err := parseTOMLValue(strings.TrimSpace(line[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		tables[table][key] = v
+	}
+	return tables, s.Err()
+}
+
+func parseTOMLValue(s string) (tomlValue, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return tomlValue{}, fmt.Errorf("malformed string %s", s)
+		}
+		return tomlValue{v}, nil
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") || strings.Contains(s[1:len(s)-1], "'") {
+			return tomlValue{}, fmt.Errorf("malformed string %s", s)
+		}
+		return tomlValue{s[1 : len(s)-1]}, nil
+	case s == "true" || s == "false":
+		return tomlValue{s == "true"}, nil
+	}
+	n, err := strconv.ParseInt(strings.Replace(s, "_", "", -1), 10, 64)
+	if err != nil {
+		return tomlValue{}, fmt.Errorf("unsupported value %s", s)
+	}
+	return tomlValue{n}, nil
+}
+
+// stripComment removes a comment from a line, minding # within strings.
+func stripComment(line string) string {
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	c, err := parseConfig(strings.NewReader(`
+# Defaults for all commands.
+profile = "work" # Not the default.
+region = 'us-east-1'
+circuit = "adder"
+
+[headers]
+X-Debug = "1"
+"X-Hash" = "#not a comment"
+
+[circuits.adder]
+type = "rca"
+width = 8
+endpoint = "http://localhost:4566"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := config{
+		profile: "work",
+		region:  "us-east-1",
+		circuit: "adder",
+		headers: map[string]string{"X-Debug": "1", "X-Hash": "#not a comment"},
+		circuits: map[string]circuitConfig{
+			"adder": {kind: "rca", width: 8, endpoint: "http://localhost:4566"},
+		},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		in, want string
+	}{
+		{`regin = "x"`, `unknown key "regin"`},
+		{`region = 1`, `key "region" has a value of the wrong type`},
+		{`region = "x`, `line 1: malformed string "x`},
+		{`region`, `line 1: want key = value`},
+		{"[tables]\na = 1", `unknown table [tables]`},
+		{"[headers]\n[headers]", `line 2: table [headers] defined twice`},
+		{"region = \"a\"\nregion = \"b\"", `line 2: key "region" defined twice`},
+		{"[circuits.c]\nwidth = 16", `circuit "c": only 8 bit ripple carry adders`},
+		{"[circuits.c]\ncolor = 1", `unknown key "circuits.c.color"`},
+		{`profile = [1, 2]`, `unsupported value [1, 2]`},
+	} {
+		_, err := parseConfig(strings.NewReader(test.in))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: got error %v, want one containing %q", test.in, err, test.want)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.toml")
+	c, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c, defaultConfig) {
+		t.Errorf("got %+v without a file, want the defaults", c)
+	}
+	if err := ioutil.WriteFile(path, []byte("bad"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(path); err == nil || !strings.HasPrefix(err.Error(), path+": line 1") {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func TestApplyCircuit(t *testing.T) {
+	keepGlobalFlags(t)
+	c := config{circuits: map[string]circuitConfig{
+		"c": {kind: "rca", width: 8, profile: "p", region: "r"},
+	}}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("name", "", "")
+	fs.StringVar(&profile, "profile", "default", "")
+	fs.StringVar(&region, "region", "default", "")
+	fs.StringVar(&endpoint, "endpoint", "default", "")
+	if err := fs.Parse([]string{"-name", "c", "-region", "flag"}); err != nil {
+		t.Fatal(err)
+	}
+	c.applyCircuit(fs)
+	if profile != "p" || region != "flag" || endpoint != "default" {
+		t.Errorf("got profile %q, region %q, endpoint %q", profile, region, endpoint)
+	}
+}
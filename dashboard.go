@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// cwDashboard is the body of a CloudWatch dashboard, see
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/CloudWatch-Dashboard-Body-Structure.html.
+type cwDashboard struct {
+	Widgets []cwWidget `json:"widgets"`
+}
+
+// cwWidget is an alarm status widget, placed on a grid 24 units wide.
+type cwWidget struct {
+	Type       string             `json:"type"`
+	X          int                `json:"x"`
+	Y          int                `json:"y"`
+	Width      int                `json:"width"`
+	Height     int                `json:"height"`
+	Properties cwWidgetProperties `json:"properties"`
+}
+
+type cwWidgetProperties struct {
+	Title  string   `json:"title"`
+	Alarms []string `json:"alarms"`
+}
+
+// dashboardBody returns the body of a CloudWatch dashboard showing the
+// states of the alarms of the circuit: the input registers at the top, a
+// widget per full adder, most significant first, and the outputs at the
+// bottom. Widgets refer to alarms by ARN, as returned by arn.
+func (rca *rippleCarryAdder) dashboardBody(arn func(name string) string) ([]byte, error) {
+	arns := func(names ...string) []string {
+		out := make([]string, len(names))
+		for i, name := range names {
+			out[i] = arn(name)
+		}
+		return out
+	}
+	widget := func(title string, x, y, width, height int, names ...string) cwWidget {
+		return cwWidget{
+			Type:   "alarm",
+			X:      x,
+			Y:      y,
+			Width:  width,
+			Height: height,
+			Properties: cwWidgetProperties{
+				Title:  title,
+				Alarms: arns(names...),
+			},
+		}
+	}
+	var a, b, out []string
+	for i := 7; i >= 0; i-- {
+		a = append(a, rca.adderLeftInName(i))
+		b = append(b, rca.adderRightInName(i))
+		out = append(out, rca.soutName(i))
+	}
+	var d cwDashboard
+	d.Widgets = append(d.Widgets,
+		widget("A", 0, 0, 12, 3, a...),
+		widget("B", 12, 0, 12, 3, b...))
+	for i := 7; i >= 0; i-- {
+		var names []string
+		if i == 0 {
+			// The carry in of the least significant adder is part of no
+			// other widget.
+			names = append(names, rca.adderCarryInName(0))
+		}
+		for _, s := range rca.adders[i].alarms() {
+			names = append(names, s.name)
+		}
+		n := 7 - i
+		d.Widgets = append(d.Widgets, widget(fmt.Sprintf("Full adder %d", i), 6*(n%4), 3+4*(n/4), 6, 4, names...))
+	}
+	d.Widgets = append(d.Widgets, widget("Sum and overflow", 0, 11, 24, 3, append([]string{rca.overflowName()}, out...)...))
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// alarmARNs returns the ARNs of the named alarms, which must exist.
+func alarmARNs(ctx context.Context, cw *cloudwatch.CloudWatch, names []string) (map[string]string, error) {
+	arns := make(map[string]string)
+	err := describeNamed(ctx, cw, names, func(a *cloudwatch.CompositeAlarm) {
+		arns[*a.AlarmName] = aws.StringValue(a.AlarmArn)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, name := range names {
+		if _, ok := arns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &alarmsNotFoundError{missing: missing}
+	}
+	return arns, nil
+}
+
+// dashboard returns the body of a CloudWatch dashboard for the deployed
+// circuit, and puts it as the named dashboard, unless the name is empty.
+func (rca *rippleCarryAdder) dashboard(ctx context.Context, name string) ([]byte, error) {
+	names := rca.alarmNames()
+	arns, err := alarmARNs(ctx, rca.cw, names)
+	if err != nil {
+		return nil, err
+	}
+	body, err := rca.dashboardBody(func(name string) string { return arns[name] })
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		return body, nil
+	}
+	if verbose {
+		log.Printf("Putting dashboard %q", name)
+	}
+	output, err := rca.cw.PutDashboardWithContext(ctx, &cloudwatch.PutDashboardInput{
+		DashboardName: aws.String(name),
+		DashboardBody: aws.String(string(body)),
+	}, requestOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("error putting dashboard %q: %w", name, apiError(err))
+	}
+	for _, m := range output.DashboardValidationMessages {
+		log.Printf("Dashboard %q: %s: %s", name, aws.StringValue(m.DataPath), aws.StringValue(m.Message))
+	}
+	return body, nil
+}
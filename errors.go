@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// alarmsNotFoundError reports alarms expected to exist that don't, typically
+// because the circuit was never built, or only partially.
+type alarmsNotFoundError struct {
+	missing []string
+
+	// States of the other alarms involved, which were found, if known.
+	states map[string]string
+}
+
+func (e *alarmsNotFoundError) Error() string {
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "%d alarms not found: %s", len(e.missing), strings.Join(quoteAll(e.missing), ", "))
+	if len(e.states) > 0 {
+		var found []string
+		for name, state := range e.states {
+			found = append(found, fmt.Sprintf("%q is %s", name, state))
+		}
+		sort.Strings(found)
+		_, _ = fmt.Fprintf(&b, " (while %s)", strings.Join(found, ", "))
+	}
+	return b.String()
+}
+
+// throttledError reports that CloudWatch refused a call because of rate
+// limits, even after retrying.
+type throttledError struct {
+	err error
+}
+
+func (e *throttledError) Error() string {
+	return fmt.Sprintf("throttled: %v", e.err)
+}
+
+func (e *throttledError) Unwrap() error {
+	return e.err
+}
+
+// validationError reports that CloudWatch rejected the parameters of a call,
+// e.g., an alarm name that is too long, or a malformed rule.
+type validationError struct {
+	err error
+}
+
+func (e *validationError) Error() string {
+	return fmt.Sprintf("invalid request: %v", e.err)
+}
+
+func (e *validationError) Unwrap() error {
+	return e.err
+}
+
+// inconsistentReadError reports a read that contradicts itself, or earlier
+// reads, as can happen while CloudWatch is still propagating changes.
+type inconsistentReadError struct {
+	reason string
+}
+
+func (e *inconsistentReadError) Error() string {
+	return fmt.Sprintf("inconsistent read: %s", e.reason)
+}
+
+// rolledBackError reports a build that failed, and whose alarms were deleted.
+type rolledBackError struct {
+	err error
+}
+
+func (e *rolledBackError) Error() string {
+	return fmt.Sprintf("build rolled back: %v", e.err)
+}
+
+func (e *rolledBackError) Unwrap() error {
+	return e.err
+}
+
+// apiError converts an error returned by the AWS SDK for a call involving the
+// named alarms to one of the typed errors above, if it is one of the kinds
+// they describe; otherwise, it returns err.
+func apiError(err error, names ...string) error {
+	var ae awserr.Error
+	if !errors.As(err, &ae) {
+		return err
+	}
+	switch ae.Code() {
+	case request.CanceledErrorCode:
+		// Make errors.Is work with context.Canceled and
+		// context.DeadlineExceeded.
+		if orig := ae.OrigErr(); orig != nil {
+			return fmt.Errorf("%s: %w", ae.Message(), orig)
+		}
+	case "ResourceNotFound", "ResourceNotFoundException":
+		return &alarmsNotFoundError{missing: names}
+	case "Throttling", "ThrottlingException", "RequestLimitExceeded":
+		return &throttledError{err: err}
+	case "ValidationError", "InvalidParameterValue", "InvalidParameterCombination", "MissingParameter":
+		return &validationError{err: err}
+	}
+	return err
+}
+
+// hint suggests what to do about an error, or returns the empty string.
+func hint(err error) string {
+	var (
+		rolledBack   *rolledBackError
+		notFound     *alarmsNotFoundError
+		throttled    *throttledError
+		validation   *validationError
+		inconsistent *inconsistentReadError
+	)
+	switch {
+	case errors.As(err, &rolledBack) && errors.Is(err, context.Canceled):
+		return "Use cac build -rollback=false to keep what an interrupted build put, then cac build -resume to continue."
+	case errors.Is(err, context.DeadlineExceeded):
+		return "Raise -timeout or -call-timeout."
+	case errors.As(err, &notFound):
+		return "Is the circuit built? Run cac build first, or cac build -resume to complete an interrupted build."
+	case errors.As(err, &throttled):
+		return "Lower -rate or -workers, or try again later."
+	case errors.As(err, &validation):
+		return "Check the circuit -name and the -region, -endpoint and -headers flags."
+	case errors.As(err, &inconsistent):
+		return "CloudWatch may still be propagating changes; try again in a few seconds."
+	}
+	return ""
+}
+
+// fatal logs the error, and a hint about what to do about it, and exits.
+func fatal(err error) {
+	log.Print(err)
+	if h := hint(err); h != "" {
+		log.Print("Hint: ", h)
+	}
+	os.Exit(1)
+}
+
+func quoteAll(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = fmt.Sprintf("%q", n)
+	}
+	return quoted
+}
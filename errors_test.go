@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+func TestAlarmsNotFoundError(t *testing.T) {
+	err := &alarmsNotFoundError{
+		missing: []string{"sout:ha:ha2:fa:adder3:rca:c"},
+		states: map[string]string{
+			"sout:ha:ha2:fa:adder0:rca:c": "OK",
+			"cout:fa:adder7:rca:c":        "ALARM",
+		},
+	}
+	want := `1 alarms not found: "sout:ha:ha2:fa:adder3:rca:c" (while "cout:fa:adder7:rca:c" is ALARM, "sout:ha:ha2:fa:adder0:rca:c" is OK)`
+	if got := err.Error(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	for _, test := range []struct {
+		code     string
+		wantHint bool
+	}{
+		{"ResourceNotFound", true},
+		{"Throttling", true},
+		{"ValidationError", true},
+		{"InternalFailure", false},
+	} {
+		err := apiError(awserr.New(test.code, "message", nil), "name")
+		wrapped := fmt.Errorf("doing something: %w", err)
+		if got := hint(wrapped) != ""; got != test.wantHint {
+			t.Errorf("%s: got hint %q", test.code, hint(wrapped))
+		}
+		var ae awserr.Error
+		if !errors.As(wrapped, &ae) && test.code != "ResourceNotFound" {
+			t.Errorf("%s: the original error is lost", test.code)
+		}
+	}
+	var notFound *alarmsNotFoundError
+	err := apiError(awserr.New("ResourceNotFound", "message", nil), "name")
+	if !errors.As(err, &notFound) || len(notFound.missing) != 1 || notFound.missing[0] != "name" {
+		t.Errorf("got %v, want name not found", err)
+	}
+	err = apiError(awserr.New(request.CanceledErrorCode, "request context canceled", context.DeadlineExceeded))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want deadline exceeded", err)
+	}
+	if h := hint(fmt.Errorf("adding: %w", context.Canceled)); h != "" {
+		t.Errorf("got hint %q for a cancelled command other than build", h)
+	}
+	if h := hint(&rolledBackError{err: context.Canceled}); !strings.Contains(h, "-resume") {
+		t.Errorf("got hint %q for a cancelled build, want one suggesting -resume", h)
+	}
+	plain := errors.New("plain")
+	if got := apiError(plain); got != plain {
+		t.Errorf("got %v, want %v", got, plain)
+	}
+}
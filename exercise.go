@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// operands is a pair of numbers to add.
+type operands struct {
+	a, b uint8
+}
+
+// parseOperand parses a number that fits the adder's registers.
+func parseOperand(s string) (uint8, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(s), 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("operand %q is not a number from 0 to 255", s)
+	}
+	return uint8(n), nil
+}
+
+// parseOperands reads pairs of operands, one pair per line, separated by
+// spaces, a comma, or a plus sign, e.g., "17 250", "17,250" or "17+250".
+// Blank lines and lines starting with # are skipped.
+func parseOperands(r io.Reader) (pairs []operands, err error) {
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == '+' || r == ' ' || r == '\t'
+		})
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want 2 operands, got %q", n, line)
+		}
+		a, err := parseOperand(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		b, err := parseOperand(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		pairs = append(pairs, operands{a: a, b: b})
+	}
+	return pairs, s.Err()
+}
+
+// exerciseResult is the outcome of a single addition on the circuit.
+type exerciseResult struct {
+	operands
+	sum      register
+	overflow bool
+
+	// From setting the inputs to the outputs settling.
+	latency time.Duration
+
+	// Whether sum and overflow are correct.
+	ok bool
+}
+
+// add sets the inputs to a and b, and waits for the outputs to settle, which
+// they do as soon as they show the right sum.
+func (rca *rippleCarryAdder) add(ctx context.Context, a, b uint8, opts waitOptions) (r exerciseResult, err error) {
+	r.operands = operands{a: a, b: b}
+	opts.want = rca.encodeOutputs(toRegister(a+b), uint16(a)+uint16(b) > 255)
+	since := time.Now()
+	if err := rca.setInputs(ctx, toRegister(a), toRegister(b)); err != nil {
+		return r, fmt.Errorf("setting inputs for %d+%d: %w", a, b, err)
+	}
+	r.sum, r.overflow, err = rca.waitForOutputs(ctx, since, opts)
+	if err != nil {
+		return r, fmt.Errorf("reading outputs for %d+%d: %w", a, b, err)
+	}
+	r.latency = time.Since(since)
+	_, r.ok = checkAddition(a, b, r.sum, r.overflow)
+	return r, nil
+}
+
+// exerciseSummary aggregates the results of repeated exercises.
+type exerciseSummary struct {
+	runs      int
+	successes int
+	min, max  time.Duration
+	total     time.Duration
+}
+
+func summarize(results []exerciseResult) (s exerciseSummary) {
+	for i, r := range results {
+		s.runs++
+		if r.ok {
+			s.successes++
+		}
+		s.total += r.latency
+		if i == 0 || r.latency < s.min {
+			s.min = r.latency
+		}
+		if r.latency > s.max {
+			s.max = r.latency
+		}
+	}
+	return
+}
+
+func (s exerciseSummary) String() string {
+	if s.runs == 0 {
+		return "no runs"
+	}
+	return fmt.Sprintf("%d/%d succeeded (%.1f%%), latency min %v, avg %v, max %v",
+		s.successes, s.runs, 100*float64(s.successes)/float64(s.runs),
+		s.min.Round(time.Millisecond),
+		(s.total / time.Duration(s.runs)).Round(time.Millisecond),
+		s.max.Round(time.Millisecond))
+}
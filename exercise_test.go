@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseOperands(t *testing.T) {
+	input := `# Failing additions.
+17 250
+17,250
+
+  0+255
+255	1
+`
+	want := []operands{{17, 250}, {17, 250}, {0, 255}, {255, 1}}
+	got, err := parseOperands(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, bad := range []string{"1", "1 2 3", "256 1", "-1 1", "a b"} {
+		if _, err := parseOperands(strings.NewReader(bad)); err == nil {
+			t.Errorf("got no error for %q", bad)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	results := []exerciseResult{
+		{ok: true, latency: 2 * time.Second},
+		{ok: false, latency: 4 * time.Second},
+		{ok: true, latency: 3 * time.Second},
+	}
+	want := "2/3 succeeded (66.7%), latency min 2s, avg 3s, max 4s"
+	if got := summarize(results).String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// mismatch records an addition for which the circuit gave a wrong result.
+type mismatch struct {
+	a, b     uint8
+	sum      register
+	overflow bool
+
+	// The sum bits that are wrong, and whether the overflow bit is.
+	wrongBits     register
+	wrongOverflow bool
+}
+
+// verification summarizes the additions checked by verifyExhaustive.
+type verification struct {
+	checked    int
+	mismatches []mismatch
+	elapsed    time.Duration
+}
+
+// checkAddition compares the outputs of the circuit for a+b with the expected
+// ones, and returns the mismatch if they differ.
+func checkAddition(a, b uint8, sum register, overflow bool) (m mismatch, ok bool) {
+	want := uint16(a) + uint16(b)
+	wantSum := toRegister(uint8(want))
+	m = mismatch{
+		a:             a,
+		b:             b,
+		sum:           sum,
+		overflow:      overflow,
+		wrongOverflow: overflow != (want > 255),
+	}
+	ok = !m.wrongOverflow
+	for i := range sum {
+		m.wrongBits[i] = sum[i] != wantSum[i]
+		ok = ok && !m.wrongBits[i]
+	}
+	return m, ok
+}
+
+// verifyExhaustive adds pairs of operands and checks the results. The pairs
+// are taken in order, a being the most significant byte of the pair index
+// and b the least significant one, starting from index 0 and advancing by
+// stride. If limit is positive, at most limit pairs are checked. The
+// function progress, if not nil, is called after each addition. On error,
+// the verification so far is returned along with the error.
+func (rca *rippleCarryAdder) verifyExhaustive(ctx context.Context, opts waitOptions, stride, limit int, progress func(v *verification)) (v verification, err error) {
+	if stride < 1 {
+		stride = 1
+	}
+	start := time.Now()
+	defer func() { v.elapsed = time.Since(start) }()
+	for i := 0; i < 1<<16 && (limit <= 0 || v.checked < limit); i += stride {
+		a, b := uint8(i>>8), uint8(i)
+		r, err := rca.add(ctx, a, b, opts)
+		if err != nil {
+			return v, err
+		}
+		v.checked++
+		if m, ok := checkAddition(a, b, r.sum, r.overflow); !ok {
+			v.mismatches = append(v.mismatches, m)
+		}
+		if progress != nil {
+			progress(&v)
+		}
+	}
+	return v, nil
+}
+
+// writeReport writes a summary of the verification, listing the mismatches
+// with their wrong bits marked by a caret.
+func writeReport(w io.Writer, v verification) error {
+	_, _ = fmt.Fprintf(w, "Checked %d additions in %v, %d mismatches.\n", v.checked, v.elapsed.Round(time.Second), len(v.mismatches))
+	if len(v.mismatches) == 0 {
+		return nil
+	}
+	_, _ = fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "A\tB\tWANT\tGOT\tWRONG\tOVERFLOW")
+	for _, m := range v.mismatches {
+		want := uint16(m.a) + uint16(m.b)
+		var wrong [8]byte
+		for i := range m.wrongBits {
+			wrong[7-i] = ' '
+			if m.wrongBits[i] {
+				wrong[7-i] = '^'
+			}
+		}
+		overflow := fmt.Sprintf("%t", m.overflow)
+		if m.wrongOverflow {
+			overflow += " (wrong)"
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%d\t%s (%d)\t%s (%d)\t%s\t%s\n",
+			m.a, m.b,
+			toRegister(uint8(want)), uint8(want),
+			m.sum, fromRegister(m.sum),
+			wrong[:], overflow)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckAddition(t *testing.T) {
+	if _, ok := checkAddition(25, 87, toRegister(112), false); !ok {
+		t.Error("got mismatch for correct sum")
+	}
+	if _, ok := checkAddition(200, 100, toRegister(44), true); !ok {
+		t.Error("got mismatch for correct overflowing sum")
+	}
+	m, ok := checkAddition(200, 100, toRegister(46), false)
+	if ok {
+		t.Fatal("got no mismatch for wrong sum")
+	}
+	if got, want := m.wrongBits.String(), "00000010"; got != want {
+		t.Errorf("got wrong bits %s, want %s", got, want)
+	}
+	if !m.wrongOverflow {
+		t.Error("got overflow bit right, want wrong")
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	m, _ := checkAddition(3, 5, toRegister(12), false)
+	v := verification{
+		checked:    256,
+		mismatches: []mismatch{m},
+		elapsed:    90 * time.Second,
+	}
+	var b strings.Builder
+	if err := writeReport(&b, v); err != nil {
+		t.Fatal(err)
+	}
+	want := `Checked 256 additions in 1m30s, 1 mismatches.
+
+A  B  WANT          GOT            WRONG     OVERFLOW
+3  5  00001000 (8)  00001100 (12)       ^    false
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
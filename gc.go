@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// schemePattern matches the names of the alarms created by the circuits,
+// e.g., cout:ha:ha1:fa:adder0:rca:computer or lin3:rca:computer.
+var schemePattern = regexp.MustCompile(`^(?:(?:cout|sout):(?:ha|fa):|(?:lin|rin)\d+:rca:|ground:rca:)`)
+
+// allRules returns the rules of all composite alarms in the account and
+// region, and the times they were last configured, keyed by alarm name.
+func allRules(ctx context.Context, cw *cloudwatch.CloudWatch) (map[string]string, map[string]time.Time, error) {
+	rules := make(map[string]string)
+	configured := make(map[string]time.Time)
+	input := &cloudwatch.DescribeAlarmsInput{
+		AlarmTypes: []*string{aws.String(cloudwatch.AlarmTypeCompositeAlarm)},
+	}
+	err := describeComposite(ctx, cw, input, func(a *cloudwatch.CompositeAlarm) {
+		rules[*a.AlarmName] = aws.StringValue(a.AlarmRule)
+		configured[*a.AlarmName] = aws.TimeValue(a.AlarmConfigurationUpdatedTimestamp)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return rules, configured, nil
+}
+
+// schemeCircuit returns the name of the ripple-carry adder an alarm belongs
+// to according to its name, or the empty string.
+func schemeCircuit(alarm string) string {
+	if i := strings.Index(alarm, ":rca:"); i >= 0 {
+		return alarm[i+len(":rca:"):]
+	}
+	return ""
+}
+
+// orphans returns the names of the alarms that belong to cac, by naming
+// scheme or by tag, but are not reachable from any complete circuit, nor from
+// any incomplete circuit for which keep returns true, e.g., one still being
+// built. A circuit is complete if all the alarms of its naming scheme exist.
+// Alarms that don't belong to cac are considered reachable too, together with
+// what their rules reference, so that no alarm someone else depends on is
+// reported. The rules are those of all composite alarms in the account, and
+// tagged maps circuit names to the names of their tagged alarms.
+func orphans(rules map[string]string, tagged map[string][]string, keep func(circuit string) bool) []string {
+	ours := make(map[string]bool)
+	circuits := make(map[string]bool)
+	for name := range rules {
+		if schemePattern.MatchString(name) {
+			ours[name] = true
+			if c := schemeCircuit(name); c != "" {
+				circuits[c] = true
+			}
+		}
+	}
+	for c, names := range tagged {
+		circuits[c] = true
+		for _, name := range names {
+			if _, ok := rules[name]; ok {
+				ours[name] = true
+			}
+		}
+	}
+	var stack []string
+	for c := range circuits {
+		names := newRippleCarryAdder(nil, c).alarmNames()
+		complete := true
+		for _, name := range names {
+			if _, ok := rules[name]; !ok {
+				complete = false
+				break
+			}
+		}
+		if complete || keep(c) {
+			stack = append(stack, names...)
+			stack = append(stack, tagged[c]...)
+		}
+	}
+	for name := range rules {
+		if !ours[name] {
+			stack = append(stack, name)
+		}
+	}
+	reachable := make(map[string]bool)
+	for len(stack) > 0 {
+		last := len(stack) - 1
+		name := stack[last]
+		stack = stack[:last]
+		if reachable[name] {
+			continue
+		}
+		reachable[name] = true
+		stack = append(stack, ruleRefs(rules[name])...)
+	}
+	var names []string
+	for name := range ours {
+		if !reachable[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// buildInProgress reports whether the circuit may be being built, or be
+// waiting for its build to be resumed: if it has a checkpoint file, or if
+// any of its alarms, by naming scheme or by tag, was configured since the
+// given time.
+func buildInProgress(circuit string, tagged []string, configured map[string]time.Time, since time.Time) bool {
+	if path, err := defaultCheckpointPath(circuit); err == nil {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	names := append(newRippleCarryAdder(nil, circuit).alarmNames(), tagged...)
+	for _, name := range names {
+		if configured[name].After(since) {
+			return true
+		}
+	}
+	return false
+}
+
+// confirm asks the question on w and reports whether the answer read from r
+// is yes.
+func confirm(r io.Reader, w io.Writer, question string) bool {
+	_, _ = fmt.Fprintf(w, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOrphans(t *testing.T) {
+	rules := make(map[string]string)
+	// A complete circuit.
+	for _, name := range newRippleCarryAdder(nil, "complete").alarmNames() {
+		rules[name] = "FALSE"
+	}
+	// An incomplete circuit, missing most of its alarms.
+	incomplete := newRippleCarryAdder(nil, "incomplete")
+	rules[incomplete.adderLeftInName(0)] = "FALSE"
+	rules[incomplete.adderRightInName(0)] = "FALSE"
+	rules[incomplete.adders[0].ha1.coutName()] = fmt.Sprintf("ALARM(%q) AND ALARM(%q)", incomplete.adderLeftInName(0), incomplete.adderRightInName(0))
+	// Left over from a half adder test, found by tag and scheme.
+	rules["left:1234"] = "FALSE"
+	rules["right:1234"] = "FALSE"
+	rules["cout:ha:test:1234"] = `ALARM("left:1234") AND ALARM("right:1234")`
+	// Someone else's alarm depending on the incomplete circuit.
+	rules["pager"] = fmt.Sprintf("ALARM(%q)", incomplete.adderLeftInName(0))
+	tagged := map[string][]string{
+		"test:1234": {"cout:ha:test:1234", "left:1234", "deleted:1234"},
+	}
+	want := []string{
+		incomplete.adders[0].ha1.coutName(),
+		"cout:ha:test:1234",
+		"left:1234",
+		incomplete.adderRightInName(0),
+	}
+	none := func(string) bool { return false }
+	if got := orphans(rules, tagged, none); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// Incomplete circuits being built are kept.
+	building := func(c string) bool { return c == "incomplete" || c == "test:1234" }
+	if got := orphans(rules, tagged, building); len(got) != 0 {
+		t.Errorf("got %q, want none", got)
+	}
+}
+
+func TestBuildInProgress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old, ok := os.LookupEnv("XDG_CACHE_HOME")
+	defer func() {
+		if ok {
+			os.Setenv("XDG_CACHE_HOME", old)
+		} else {
+			os.Unsetenv("XDG_CACHE_HOME")
+		}
+	}()
+	os.Setenv("XDG_CACHE_HOME", dir)
+	now := time.Now()
+	rca := newRippleCarryAdder(nil, "test")
+	configured := map[string]time.Time{
+		rca.adderLeftInName(0): now.Add(-2 * time.Hour),
+		"tagged":               now.Add(-2 * time.Hour),
+	}
+	since := now.Add(-time.Hour)
+	if buildInProgress("test", []string{"tagged"}, configured, since) {
+		t.Error("old circuit without checkpoint in progress")
+	}
+	configured["tagged"] = now
+	if !buildInProgress("test", []string{"tagged"}, configured, since) {
+		t.Error("recently configured circuit not in progress")
+	}
+	configured["tagged"] = now.Add(-2 * time.Hour)
+	path, err := defaultCheckpointPath("test")
+	if err != nil {
+		t.Skip(err)
+	}
+	cp, err := openCheckpoint(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cp.close()
+	if !buildInProgress("test", []string{"tagged"}, configured, since) {
+		t.Error("circuit with checkpoint not in progress")
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	for _, test := range []struct {
+		answer string
+		want   bool
+	}{
+		{"y\n", true},
+		{" Yes \n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	} {
+		var b strings.Builder
+		if got := confirm(strings.NewReader(test.answer), &b, "Delete?"); got != test.want {
+			t.Errorf("answer %q: got %t, want %t", test.answer, got, test.want)
+		}
+		if got, want := b.String(), "Delete? [y/N] "; got != want {
+			t.Errorf("got prompt %q, want %q", got, want)
+		}
+	}
+}
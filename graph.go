@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// circuitGraph is a directed graph of alarms, with an edge from each alarm
+// to each alarm whose rule references it, i.e., in the direction signals
+// flow.
+type circuitGraph struct {
+	nodes map[string]*graphNode
+	edges map[graphEdge]bool
+
+	// Rows of nodes to lay out side by side, in order, e.g., the bits of a
+	// register from the most significant.
+	rows []graphRow
+
+	// Dropped from node names in labels, typically the suffix common to
+	// all the alarms of the circuit.
+	suffix string
+}
+
+type graphNode struct {
+	name string
+
+	// The kind of gate, e.g., AND, or input, or empty if unknown.
+	gate string
+
+	// The rule and state of the alarm, empty if unknown.
+	rule  string
+	state string
+}
+
+type graphEdge struct {
+	from, to string
+}
+
+// graphRow is a row of nodes, at the top (rank min) or the bottom (rank max)
+// of the graph.
+type graphRow struct {
+	rank  string
+	names []string
+}
+
+func newCircuitGraph() *circuitGraph {
+	return &circuitGraph{
+		nodes: make(map[string]*graphNode),
+		edges: make(map[graphEdge]bool),
+	}
+}
+
+func (g *circuitGraph) node(name string) *graphNode {
+	n, ok := g.nodes[name]
+	if !ok {
+		n = &graphNode{name: name}
+		g.nodes[name] = n
+	}
+	return n
+}
+
+func (g *circuitGraph) addEdge(from, to string) {
+	g.node(from)
+	g.node(to)
+	g.edges[graphEdge{from: from, to: to}] = true
+}
+
+// sortedEdges returns the edges ordered by target, then source.
+func (g *circuitGraph) sortedEdges() []graphEdge {
+	edges := make([]graphEdge, 0, len(g.edges))
+	for e := range g.edges {
+		edges = append(edges, e)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].to != edges[j].to {
+			return edges[i].to < edges[j].to
+		}
+		return edges[i].from < edges[j].from
+	})
+	return edges
+}
+
+func (g *circuitGraph) sortedNodes() []*graphNode {
+	nodes := make([]*graphNode, 0, len(g.nodes))
+	for _, n := range g.nodes {
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].name < nodes[j].name })
+	return nodes
+}
+
+// gates maps alarm roles to the kind of gate the alarm implements.
+var gates = map[string]string{
+	roleInput:          "input",
+	roleGround:         "ground",
+	roleHalfAdderCarry: "AND",
+	roleHalfAdderSum:   "XOR",
+	roleAdderCarry:     "OR",
+}
+
+// specGraph builds the graph of the alarms described by specs, without any
+// API call.
+func specGraph(specs []alarmSpec) *circuitGraph {
+	g := newCircuitGraph()
+	for _, s := range specs {
+		n := g.node(s.name)
+		n.gate = gates[s.role]
+		n.rule = s.rule
+		for _, in := range s.inputs {
+			g.addEdge(in, s.name)
+		}
+	}
+	return g
+}
+
+// graph builds the graph of the circuit from its model or, if live is true,
+// from the deployed alarms, including their states.
+func (rca *rippleCarryAdder) graph(ctx context.Context, live bool) (*circuitGraph, error) {
+	g := specGraph(rca.alarms())
+	if live {
+		var err error
+		if g, err = rca.liveGraph(ctx); err != nil {
+			return nil, err
+		}
+	}
+	var a, b, out []string
+	for i := 7; i >= 0; i-- {
+		a = append(a, rca.adderLeftInName(i))
+		b = append(b, rca.adderRightInName(i))
+		out = append(out, rca.soutName(i))
+	}
+	g.rows = []graphRow{
+		{rank: "min", names: append(a, b...)},
+		{rank: "max", names: append([]string{rca.overflowName()}, out...)},
+	}
+	g.suffix = ":rca:" + rca.name
+	return g, nil
+}
+
+// liveGraph builds the graph of the deployed alarms of the circuit, walking
+// from its outputs to the alarms their rules reference, as CloudWatch
+// reports them. Gates are known from the circuit model, rules and states
+// from CloudWatch.
+func (rca *rippleCarryAdder) liveGraph(ctx context.Context) (*circuitGraph, error) {
+	g := newCircuitGraph()
+	// Stack of names of alarms of which to get the children, in order to
+	// find directed edges. Initially consists of all the 8 output bits and
+	// the overflow output bit.
+	stack := rca.outputNames()
+	seen := make(map[string]struct{})
+	for len(stack) > 0 {
+		last := len(stack) - 1
+		parentName := stack[last]
+		stack = stack[:last]
+		if _, ok := seen[parentName]; ok {
+			continue
+		}
+		childNames, err := children(ctx, rca.cw, parentName)
+		if err != nil {
+			return nil, err
+		}
+		seen[parentName] = struct{}{}
+		g.node(parentName)
+		for _, cn := range childNames {
+			if _, ok := seen[cn]; !ok {
+				stack = append(stack, cn)
+			}
+			g.addEdge(cn, parentName)
+		}
+	}
+	roles := make(map[string]string)
+	for _, s := range rca.alarms() {
+		roles[s.name] = s.role
+	}
+	var names []string
+	for name, n := range g.nodes {
+		n.gate = gates[roles[name]]
+		names = append(names, name)
+	}
+	err := describeNamed(ctx, rca.cw, names, func(a *cloudwatch.CompositeAlarm) {
+		n := g.nodes[*a.AlarmName]
+		n.rule = aws.StringValue(a.AlarmRule)
+		n.state = aws.StringValue(a.StateValue)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// stateColors are the fill colors of nodes by alarm state.
+var stateColors = map[string]string{
+	cloudwatch.StateValueAlarm:            "#f28b82",
+	cloudwatch.StateValueOk:               "#ccff90",
+	cloudwatch.StateValueInsufficientData: "#e8eaed",
+}
+
+// label returns the label of the node: its name, its gate, and its rule, with
+// the graph's suffix dropped from all alarm names.
+func (g *circuitGraph) label(n *graphNode) string {
+	lines := []string{strings.TrimSuffix(n.name, g.suffix)}
+	if n.gate != "" {
+		lines = append(lines, n.gate)
+	}
+	if n.rule != "" && n.gate != "input" && n.gate != "ground" {
+		lines = append(lines, strings.Replace(n.rule, g.suffix+`"`, `"`, -1))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// graphCluster is a group of nodes of a component of the circuit, e.g., a
+// full adder, with the groups of its subcomponents, e.g., its half adders.
+type graphCluster struct {
+	name     string
+	path     string
+	children map[string]*graphCluster
+	nodes    []*graphNode
+}
+
+func (c *graphCluster) child(name string) *graphCluster {
+	if c.children == nil {
+		c.children = make(map[string]*graphCluster)
+	}
+	child, ok := c.children[name]
+	if !ok {
+		child = &graphCluster{name: name, path: c.path + "/" + name}
+		c.children[name] = child
+	}
+	return child
+}
+
+func (c *graphCluster) sortedChildren() []*graphCluster {
+	children := make([]*graphCluster, 0, len(c.children))
+	for _, child := range c.children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].name < children[j].name })
+	return children
+}
+
+// clusters groups the nodes following the naming hierarchy. The outermost
+// component, the circuit, is the graph itself, so the root cluster holds the
+// nodes directly in it. The nodes in rows are kept in the root, too, so that
+// they line up.
+func (g *circuitGraph) clusters() *graphCluster {
+	inRow := make(map[string]bool)
+	for _, row := range g.rows {
+		for _, name := range row.names {
+			inRow[name] = true
+		}
+	}
+	root := &graphCluster{}
+	for _, n := range g.sortedNodes() {
+		c := root
+		if scopes, _ := splitAlarmName(n.name); !inRow[n.name] && len(scopes) > 1 {
+			for _, name := range scopes[1:] {
+				c = c.child(name)
+			}
+		}
+		c.nodes = append(c.nodes, n)
+	}
+	return root
+}
+
+// writeDOT writes the graph in the DOT language, readable by xdot, with a
+// cluster per component. Nodes are colored by state, if known.
+func (g *circuitGraph) writeDOT(w io.Writer) error {
+	_, _ = fmt.Fprintln(w, "digraph {")
+	_, _ = fmt.Fprintln(w, "\tnode [shape=box, style=filled, fillcolor=white];")
+	g.writeDOTCluster(w, g.clusters(), "\t")
+	for _, row := range g.rows {
+		_, _ = fmt.Fprintf(w, "\t{ rank=%s; %s; }\n", row.rank, strings.Join(quoteAll(row.names), "; "))
+		// Invisible edges keep the nodes of the row in order.
+		_, _ = fmt.Fprintf(w, "\t%s [style=invis];\n", strings.Join(quoteAll(row.names), " -> "))
+	}
+	for _, e := range g.sortedEdges() {
+		_, _ = fmt.Fprintf(w, "\t%q -> %q;\n", e.from, e.to)
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+func (g *circuitGraph) writeDOTCluster(w io.Writer, c *graphCluster, indent string) {
+	for _, n := range c.nodes {
+		attrs := fmt.Sprintf("label=%q", g.label(n))
+		if color, ok := stateColors[n.state]; ok {
+			attrs += fmt.Sprintf(", fillcolor=%q", color)
+		}
+		_, _ = fmt.Fprintf(w, "%s%q [%s];\n", indent, n.name, attrs)
+	}
+	for _, child := range c.sortedChildren() {
+		_, _ = fmt.Fprintf(w, "%ssubgraph %q {\n", indent, "cluster"+child.path)
+		_, _ = fmt.Fprintf(w, "%s\tlabel=%q;\n", indent, child.name)
+		g.writeDOTCluster(w, child, indent+"\t")
+		_, _ = fmt.Fprintf(w, "%s}\n", indent)
+	}
+}
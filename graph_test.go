@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSpecGraph(t *testing.T) {
+	g := specGraph(newRippleCarryAdder(nil, "c").alarms())
+	// Each of the 8 adders has 2 half-adders with 2 outputs depending on 2
+	// inputs each, and a carry output depending on 2 half-adder carries.
+	if got, want := len(g.edges), 80; got != want {
+		t.Errorf("got %d edges, want %d", got, want)
+	}
+	if !g.edges[graphEdge{from: "ground:rca:c", to: "cout:ha:ha2:fa:adder0:rca:c"}] {
+		t.Error("missing edge from ground to the carry of the second half-adder of adder0")
+	}
+	if !g.edges[graphEdge{from: "cout:fa:adder6:rca:c", to: "sout:ha:ha2:fa:adder7:rca:c"}] {
+		t.Error("missing edge from the carry of adder6 to the sum of adder7")
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	ha := &halfAdder{name: "h", leftIn: "l", rightIn: "r"}
+	g := specGraph(ha.alarms())
+	g.node("l").state = "ALARM"
+	g.node("l").gate = "input"
+	g.rows = []graphRow{{rank: "min", names: []string{"l", "r"}}}
+	var b strings.Builder
+	if err := g.writeDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `digraph {
+	node [shape=box, style=filled, fillcolor=white];
+	"cout:ha:h" [label="cout:ha:h\nAND\nALARM(\"l\") AND ALARM(\"r\")"];
+	"l" [label="l\ninput", fillcolor="#f28b82"];
+	"r" [label="r"];
+	"sout:ha:h" [label="sout:ha:h\nXOR\n(ALARM(\"l\") OR ALARM(\"r\")) AND NOT (ALARM(\"l\") AND ALARM(\"r\"))"];
+	{ rank=min; "l"; "r"; }
+	"l" -> "r" [style=invis];
+	"l" -> "cout:ha:h";
+	"r" -> "cout:ha:h";
+	"l" -> "sout:ha:h";
+	"r" -> "sout:ha:h";
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGraphLabels(t *testing.T) {
+	g, err := newRippleCarryAdder(nil, "c").graph(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := g.label(g.nodes["cout:fa:adder3:rca:c"]), "cout:fa:adder3\nOR\nALARM(\"cout:ha:ha1:fa:adder3\") OR ALARM(\"cout:ha:ha2:fa:adder3\")"; got != want {
+		t.Errorf("got label %q, want %q", got, want)
+	}
+	if got, want := g.label(g.nodes["lin0:rca:c"]), "lin0\ninput"; got != want {
+		t.Errorf("got label %q, want %q", got, want)
+	}
+	if got, want := g.rows[0].names[0], "lin7:rca:c"; got != want {
+		t.Errorf("got first input %q, want %q", got, want)
+	}
+	if got, want := g.rows[1].names[8], "sout:ha:ha2:fa:adder0:rca:c"; got != want {
+		t.Errorf("got last output %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// graphFormats maps the values of the -format flag to the functions writing
+// the graph in that format.
+var graphFormats = map[string]func(*circuitGraph, io.Writer) error{
+	"dot":     (*circuitGraph).writeDOT,
+	"mermaid": (*circuitGraph).writeMermaid,
+	"graphml": (*circuitGraph).writeGraphML,
+	"json":    (*circuitGraph).writeJSON,
+	"svg":     (*circuitGraph).writeSVG,
+}
+
+func graphFormatNames() []string {
+	var names []string
+	for name := range graphFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nodeIDs returns short identifiers for the nodes, for formats that don't
+// allow arbitrary strings as such.
+func (g *circuitGraph) nodeIDs() map[string]string {
+	ids := make(map[string]string)
+	for i, n := range g.sortedNodes() {
+		ids[n.name] = fmt.Sprintf("n%d", i)
+	}
+	return ids
+}
+
+// writeMermaid writes the graph as a Mermaid flowchart, with a subgraph per
+// component, suitable for embedding in Markdown documents.
+func (g *circuitGraph) writeMermaid(w io.Writer) error {
+	ids := g.nodeIDs()
+	_, _ = fmt.Fprintln(w, "flowchart TB")
+	g.writeMermaidCluster(w, g.clusters(), ids, "\t")
+	for _, e := range g.sortedEdges() {
+		_, _ = fmt.Fprintf(w, "\t%s --> %s\n", ids[e.from], ids[e.to])
+	}
+	var err error
+	for _, n := range g.sortedNodes() {
+		if color, ok := stateColors[n.state]; ok {
+			_, err = fmt.Fprintf(w, "\tstyle %s fill:%s\n", ids[n.name], color)
+		}
+	}
+	return err
+}
+
+func (g *circuitGraph) writeMermaidCluster(w io.Writer, c *graphCluster, ids map[string]string, indent string) {
+	for _, n := range c.nodes {
+		label := strings.Replace(g.label(n), `"`, "#quot;", -1)
+		label = strings.Replace(label, "\n", "<br>", -1)
+		_, _ = fmt.Fprintf(w, "%s%s[\"%s\"]\n", indent, ids[n.name], label)
+	}
+	for _, child := range c.sortedChildren() {
+		_, _ = fmt.Fprintf(w, "%ssubgraph %s [\"%s\"]\n", indent, mermaidID(child.path), child.name)
+		g.writeMermaidCluster(w, child, ids, indent+"\t")
+		_, _ = fmt.Fprintf(w, "%send\n", indent)
+	}
+}
+
+// mermaidID turns the path of a cluster into an identifier, e.g.,
+// "/adder0/ha1" into "c_adder0_ha1".
+func mermaidID(path string) string {
+	return "c" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, path)
+}
+
+// writeGraphML writes the graph in GraphML, with a nested graph per
+// component, and the gate, rule, and state of each alarm as node data.
+func (g *circuitGraph) writeGraphML(w io.Writer) error {
+	_, _ = fmt.Fprintln(w, `<?xml version="1.0" encoding="UTF-8"?>`)
+	_, _ = fmt.Fprintln(w, `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	for _, key := range []string{"gate", "rule", "state"} {
+		_, _ = fmt.Fprintf(w, "\t<key id=%q for=\"node\" attr.name=%q attr.type=\"string\"/>\n", key, key)
+	}
+	_, _ = fmt.Fprintln(w, "\t<graph id=\"circuit\" edgedefault=\"directed\">")
+	g.writeGraphMLCluster(w, g.clusters(), "\t\t")
+	for _, e := range g.sortedEdges() {
+		_, _ = fmt.Fprintf(w, "\t\t<edge source=\"%s\" target=\"%s\"/>\n", xmlEscape(e.from), xmlEscape(e.to))
+	}
+	_, _ = fmt.Fprintln(w, "\t</graph>")
+	_, err := fmt.Fprintln(w, "</graphml>")
+	return err
+}
+
+func (g *circuitGraph) writeGraphMLCluster(w io.Writer, c *graphCluster, indent string) {
+	for _, n := range c.nodes {
+		_, _ = fmt.Fprintf(w, "%s<node id=\"%s\">\n", indent, xmlEscape(n.name))
+		for _, d := range []struct{ key, value string }{{"gate", n.gate}, {"rule", n.rule}, {"state", n.state}} {
+			if d.value != "" {
+				_, _ = fmt.Fprintf(w, "%s\t<data key=%q>%s</data>\n", indent, d.key, xmlEscape(d.value))
+			}
+		}
+		_, _ = fmt.Fprintf(w, "%s</node>\n", indent)
+	}
+	for _, child := range c.sortedChildren() {
+		_, _ = fmt.Fprintf(w, "%s<node id=\"%s\">\n", indent, xmlEscape(child.path))
+		_, _ = fmt.Fprintf(w, "%s\t<graph id=\"%s:\" edgedefault=\"directed\">\n", indent, xmlEscape(child.path))
+		g.writeGraphMLCluster(w, child, indent+"\t\t")
+		_, _ = fmt.Fprintf(w, "%s\t</graph>\n", indent)
+		_, _ = fmt.Fprintf(w, "%s</node>\n", indent)
+	}
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+type jsonGraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+	Rows  [][]string `json:"rows,omitempty"`
+}
+
+type jsonNode struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes,omitempty"`
+	Gate   string   `json:"gate,omitempty"`
+	Rule   string   `json:"rule,omitempty"`
+	State  string   `json:"state,omitempty"`
+}
+
+type jsonEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// writeJSON writes the graph as a JSON object with lists of nodes, edges,
+// and rows. Each node has the scopes it belongs to, outermost first.
+func (g *circuitGraph) writeJSON(w io.Writer) error {
+	out := jsonGraph{Nodes: []jsonNode{}, Edges: []jsonEdge{}}
+	for _, n := range g.sortedNodes() {
+		scopes, _ := splitAlarmName(n.name)
+		out.Nodes = append(out.Nodes, jsonNode{
+			Name:   n.name,
+			Scopes: scopes,
+			Gate:   n.gate,
+			Rule:   n.rule,
+			State:  n.state,
+		})
+	}
+	for _, e := range g.sortedEdges() {
+		out.Edges = append(out.Edges, jsonEdge{From: e.from, To: e.to})
+	}
+	for _, row := range g.rows {
+		out.Rows = append(out.Rows, row.names)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
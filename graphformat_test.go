@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestClusters(t *testing.T) {
+	g, err := newRippleCarryAdder(nil, "c").graph(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := g.clusters()
+	// The inputs, outputs, and ground stay out of the clusters.
+	if got, want := len(root.nodes), 8+8+9+1; got != want {
+		t.Errorf("got %d nodes out of clusters, want %d", got, want)
+	}
+	if got, want := len(root.children), 8; got != want {
+		t.Fatalf("got %d clusters, want %d", got, want)
+	}
+	adder := root.children["adder3"]
+	if got, want := adder.path, "/adder3"; got != want {
+		t.Errorf("got path %q, want %q", got, want)
+	}
+	if got, want := len(adder.nodes), 1; got != want {
+		t.Errorf("got %d nodes in the full adder, want %d", got, want)
+	}
+	if got, want := len(adder.children["ha1"].nodes), 2; got != want {
+		t.Errorf("got %d nodes in the first half adder, want %d", got, want)
+	}
+	// The sum of the second half adder is an output of the circuit.
+	if got, want := len(adder.children["ha2"].nodes), 1; got != want {
+		t.Errorf("got %d nodes in the second half adder, want %d", got, want)
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+	ha := &halfAdder{name: "h", leftIn: "l", rightIn: "r"}
+	g := specGraph(ha.alarms())
+	g.node("l").state = "OK"
+	var b strings.Builder
+	if err := g.writeMermaid(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `flowchart TB
+	n0["cout:ha:h<br>AND<br>ALARM(#quot;l#quot;) AND ALARM(#quot;r#quot;)"]
+	n1["l"]
+	n2["r"]
+	n3["sout:ha:h<br>XOR<br>(ALARM(#quot;l#quot;) OR ALARM(#quot;r#quot;)) AND NOT (ALARM(#quot;l#quot;) AND ALARM(#quot;r#quot;))"]
+	n1 --> n0
+	n2 --> n0
+	n1 --> n3
+	n2 --> n3
+	style n1 fill:#ccff90
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMermaidID(t *testing.T) {
+	if got, want := mermaidID("/adder0/ha1"), "c_adder0_ha1"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	g, err := newRippleCarryAdder(nil, "c").graph(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := g.writeGraphML(&b); err != nil {
+		t.Fatal(err)
+	}
+	type node struct {
+		ID   string `xml:"id,attr"`
+		Data []struct {
+			Key   string `xml:"key,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"data"`
+	}
+	var doc struct {
+		Graph struct {
+			Nodes []node `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal([]byte(b.String()), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(doc.Graph.Edges), 80; got != want {
+		t.Errorf("got %d edges, want %d", got, want)
+	}
+	// The top-level graph has the nodes out of clusters, and one per full
+	// adder.
+	if got, want := len(doc.Graph.Nodes), 26+8; got != want {
+		t.Errorf("got %d top-level nodes, want %d", got, want)
+	}
+	for _, n := range doc.Graph.Nodes {
+		if n.ID == "ground:rca:c" {
+			if len(n.Data) != 2 || n.Data[0].Key != "gate" || n.Data[0].Value != "ground" {
+				t.Errorf("got data %+v for the ground", n.Data)
+			}
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	g, err := newRippleCarryAdder(nil, "c").graph(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := g.writeJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	var out jsonGraph
+	if err := json.Unmarshal([]byte(b.String()), &out); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(out.Edges), 80; got != want {
+		t.Errorf("got %d edges, want %d", got, want)
+	}
+	if got, want := len(out.Nodes), len(g.nodes); got != want {
+		t.Errorf("got %d nodes, want %d", got, want)
+	}
+	if got, want := len(out.Rows), 2; got != want {
+		t.Errorf("got %d rows, want %d", got, want)
+	}
+	n := out.Nodes[0]
+	if n.Name != "cout:fa:adder0:rca:c" || n.Gate != "OR" || strings.Join(n.Scopes, "/") != "c/adder0" {
+		t.Errorf("got first node %+v", n)
+	}
+}
+
+func TestSaveGraphUnknownFormat(t *testing.T) {
+	var b strings.Builder
+	err := newRippleCarryAdder(nil, "c").saveGraph(context.Background(), &b, false, "png")
+	if err == nil || !strings.Contains(err.Error(), "dot, graphml, json, mermaid, svg") {
+		t.Errorf("got error %v", err)
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 )
@@ -17,18 +19,27 @@ type halfAdder struct {
 	rightIn string
 }
 
-func (ha *halfAdder) build() error {
-	rule := fmt.Sprintf("ALARM(%q) AND ALARM(%q)", ha.leftIn, ha.rightIn)
-	err := pca(ha.cw, ha.coutName(), rule)
-	if err != nil {
-		return fmt.Errorf("could not create %q: %w", ha.coutName(), err)
-	}
-	rule = fmt.Sprintf("(ALARM(%q) OR ALARM(%q)) AND NOT (ALARM(%q) AND ALARM(%q))", ha.leftIn, ha.rightIn, ha.leftIn, ha.rightIn)
-	err = pca(ha.cw, ha.soutName(), rule)
-	if err != nil {
-		return fmt.Errorf("could not create %q: %w", ha.soutName(), err)
+func (ha *halfAdder) build(ctx context.Context, tx *buildTx) error {
+	return tx.build(ctx, ha.alarms())
+}
+
+// alarms describes the output alarms of the half-adder.
+func (ha *halfAdder) alarms() []alarmSpec {
+	inputs := []string{ha.leftIn, ha.rightIn}
+	return []alarmSpec{
+		{
+			name:   ha.coutName(),
+			rule:   fmt.Sprintf("ALARM(%q) AND ALARM(%q)", ha.leftIn, ha.rightIn),
+			inputs: inputs,
+			role:   roleHalfAdderCarry,
+		},
+		{
+			name:   ha.soutName(),
+			rule:   fmt.Sprintf("(ALARM(%q) OR ALARM(%q)) AND NOT (ALARM(%q) AND ALARM(%q))", ha.leftIn, ha.rightIn, ha.leftIn, ha.rightIn),
+			inputs: inputs,
+			role:   roleHalfAdderSum,
+		},
 	}
-	return nil
 }
 
 func (ha *halfAdder) coutName() string {
@@ -39,19 +50,32 @@ func (ha *halfAdder) soutName() string {
 	return fmt.Sprintf("sout:ha:%s", ha.name)
 }
 
-func (ha *halfAdder) setInputs(leftIn bool, rightIn bool) error {
-	err := sas(ha.cw, ha.leftIn, leftIn)
+func (ha *halfAdder) setInputs(ctx context.Context, leftIn bool, rightIn bool) error {
+	err := sas(ctx, ha.cw, ha.leftIn, leftIn)
 	if err != nil {
 		return err
 	}
-	return sas(ha.cw, ha.rightIn, rightIn)
+	return sas(ctx, ha.cw, ha.rightIn, rightIn)
 }
 
-func (ha *halfAdder) readOutputs() (carry bool, sum bool, err error) {
-	states, err := describeStates(ha.cw, []string{
+func (ha *halfAdder) outputNames() []string {
+	return []string{
 		ha.coutName(),
 		ha.soutName(),
-	})
+	}
+}
+
+func (ha *halfAdder) readOutputs(ctx context.Context) (carry bool, sum bool, err error) {
+	return ha.decodeOutputs(describeStates(ctx, ha.cw, ha.outputNames()))
+}
+
+// waitForOutputs is like readOutputs, but waits for the outputs to settle
+// after the inputs were set at the given time.
+func (ha *halfAdder) waitForOutputs(ctx context.Context, since time.Time, opts waitOptions) (carry bool, sum bool, err error) {
+	return ha.decodeOutputs(waitForOutputs(ctx, ha.cw, ha.outputNames(), since, opts))
+}
+
+func (ha *halfAdder) decodeOutputs(states []string, err error) (carry bool, sum bool, _ error) {
 	if err != nil {
 		return false, false, err
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"testing"
@@ -14,20 +15,21 @@ func TestHalfAdderTruthTable(t *testing.T) {
 	b := make([]byte, 16)
 	rand.Read(b)
 	suffix := fmt.Sprintf(":%x", b)
+	ctx := context.Background()
 	cw, err := defaultClient()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := pcab(cw, "left"+suffix, false); err != nil {
+	if err := pcab(ctx, cw, "left"+suffix, false); err != nil {
 		t.Fatal(err)
 	}
-	defer func() { _ = daRecursive(cw, "left"+suffix) }()
-	if err := pcab(cw, "right"+suffix, false); err != nil {
+	defer func() { _ = daRecursive(ctx, cw, "left"+suffix) }()
+	if err := pcab(ctx, cw, "right"+suffix, false); err != nil {
 		t.Fatal(err)
 	}
-	defer func() { _ = daRecursive(cw, "right"+suffix) }()
+	defer func() { _ = daRecursive(ctx, cw, "right"+suffix) }()
 	ha := &halfAdder{cw: cw, name: "test" + suffix, leftIn: "left" + suffix, rightIn: "right" + suffix}
-	if err := ha.build(); err != nil {
+	if err := ha.build(ctx, newBuildTx(cw, "test"+suffix)); err != nil {
 		t.Fatal(err)
 	}
 	truthTable := [][4]bool{
@@ -38,12 +40,14 @@ func TestHalfAdderTruthTable(t *testing.T) {
 	}
 	for _, test := range truthTable {
 		t.Run("", func(t *testing.T) {
-			err := ha.setInputs(test[0], test[1])
+			since := time.Now()
+			err := ha.setInputs(ctx, test[0], test[1])
 			if err != nil {
 				t.Fatal(err)
 			}
-			time.Sleep(evaluationLatency)
-			carry, sum, err := ha.readOutputs()
+			opts := defaultWaitOptions
+			opts.want = encodeStates(test[2], test[3])
+			carry, sum, err := ha.waitForOutputs(ctx, since, opts)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+)
+
+// Keys of the tags attached to every alarm the tool creates.
+const (
+	tagCircuit = "cac:circuit"
+	tagRole    = "cac:role"
+	tagVersion = "cac:version"
+)
+
+// Values of the role tag, i.e., what an alarm is within its circuit.
+const (
+	roleInput          = "input"
+	roleGround         = "ground"
+	roleHalfAdderCarry = "ha-carry"
+	roleHalfAdderSum   = "ha-sum"
+	roleAdderCarry     = "fa-carry"
+)
+
+// maxAlarmNames is the maximum number of alarm names DescribeAlarms and
+// DeleteAlarms accept in a single call.
+const maxAlarmNames = 100
+
+type circuitSummary struct {
+	name      string
+	alarms    []string
+	lastBuilt time.Time
+}
+
+// taggedAlarms returns the names of the alarms tagged as part of a circuit,
+// grouped by circuit name. If circuit is not empty, only the alarms of that
+// circuit are returned.
+func taggedAlarms(ctx context.Context, tagging *resourcegroupstaggingapi.ResourceGroupsTaggingAPI, circuit string) (map[string][]string, error) {
+	filter := &resourcegroupstaggingapi.TagFilter{
+		Key: aws.String(tagCircuit),
+	}
+	if circuit != "" {
+		filter.Values = []*string{aws.String(circuit)}
+	}
+	input := &resourcegroupstaggingapi.GetResourcesInput{
+		ResourceTypeFilters: []*string{aws.String("cloudwatch:alarm")},
+		TagFilters:          []*resourcegroupstaggingapi.TagFilter{filter},
+	}
+	byCircuit := make(map[string][]string)
+	err := tagging.GetResourcesPagesWithContext(ctx, input, func(output *resourcegroupstaggingapi.GetResourcesOutput, _ bool) bool {
+		for _, mapping := range output.ResourceTagMappingList {
+			for _, tag := range mapping.Tags {
+				if *tag.Key == tagCircuit {
+					name := alarmNameFromARN(*mapping.ResourceARN)
+					byCircuit[*tag.Value] = append(byCircuit[*tag.Value], name)
+				}
+			}
+		}
+		return true
+	}, requestOptions...)
+	if err != nil {
+		return nil, apiError(err)
+	}
+	return byCircuit, nil
+}
+
+// alarmNameFromARN extracts the alarm name from an alarm ARN, which is of the
+// form arn:partition:cloudwatch:region:account:alarm:name. The name itself
+// may contain colons.
+func alarmNameFromARN(arn string) string {
+	parts := strings.SplitN(arn, ":", 7)
+	if len(parts) < 7 {
+		return arn
+	}
+	return parts[6]
+}
+
+// listCircuits finds all circuits in the account and region by the tags of
+// their alarms. The tagging API can still list alarms for a while after they
+// are deleted, so only the alarms that exist are counted, and circuits with
+// none are left out. The last build time of a circuit is the last time any
+// of its alarms was configured.
+func listCircuits(ctx context.Context, cw *cloudwatch.CloudWatch, tagging *resourcegroupstaggingapi.ResourceGroupsTaggingAPI) ([]circuitSummary, error) {
+	byCircuit, err := taggedAlarms(ctx, tagging, "")
+	if err != nil {
+		return nil, err
+	}
+	var summaries []circuitSummary
+	for name, alarms := range byCircuit {
+		s := circuitSummary{name: name}
+		err := describeNamed(ctx, cw, alarms, func(a *cloudwatch.CompositeAlarm) {
+			s.alarms = append(s.alarms, *a.AlarmName)
+			if t := aws.TimeValue(a.AlarmConfigurationUpdatedTimestamp); t.After(s.lastBuilt) {
+				s.lastBuilt = t
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(s.alarms) > 0 {
+			summaries = append(summaries, s)
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].name < summaries[j].name
+	})
+	return summaries, nil
+}
+
+func printCircuits(w io.Writer, summaries []circuitSummary) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "CIRCUIT\tALARMS\tLAST BUILT")
+	for _, s := range summaries {
+		lastBuilt := "-"
+		if !s.lastBuilt.IsZero() {
+			lastBuilt = s.lastBuilt.Local().Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\n", s.name, len(s.alarms), lastBuilt)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestAlarmNameFromARN(t *testing.T) {
+	for _, test := range []struct {
+		arn  string
+		want string
+	}{
+		{"arn:aws:cloudwatch:eu-west-1:123456789012:alarm:ground:rca:computer", "ground:rca:computer"},
+		{"arn:aws-cn:cloudwatch:cn-north-1:123456789012:alarm:left", "left"},
+		{"not-an-arn", "not-an-arn"},
+	} {
+		if got := alarmNameFromARN(test.arn); got != test.want {
+			t.Errorf("alarmNameFromARN(%q) = %q, want %q", test.arn, got, test.want)
+		}
+	}
+}
@@ -1,23 +1,27 @@
 package main
 
 import (
-	"flag"
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
-	"sort"
+	"os/signal"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/endpoints"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
 )
 
+// version is recorded in the tags of the alarms the tool creates. Release
+// builds set it with -ldflags "-X main.version=...".
+var version = "devel"
+
 var (
 	profile  string
 	region   string
@@ -25,6 +29,10 @@ var (
 	headers  string
 	verbose  bool
 
+	// If positive, the deadline for each API call, on top of any deadline of
+	// the context the call is made with.
+	callTimeout time.Duration
+
 	headerMap = make(map[string]string)
 )
 
@@ -34,6 +42,17 @@ func addHeaders(r *request.Request) {
 	}
 }
 
+func addCallTimeout(r *request.Request) {
+	if callTimeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), callTimeout)
+		r.SetContext(ctx)
+		r.Handlers.Complete.PushBack(func(*request.Request) { cancel() })
+	}
+}
+
+// requestOptions are applied to every API request.
+var requestOptions = []request.Option{addHeaders, addCallTimeout}
+
 func defaultClient() (*cloudwatch.CloudWatch, error) {
 	sess, err := session.NewSession(&aws.Config{
 		Region:      aws.String(region),
@@ -46,30 +65,76 @@ func defaultClient() (*cloudwatch.CloudWatch, error) {
 	return cloudwatch.New(sess), nil
 }
 
-func pca(cw *cloudwatch.CloudWatch, name, rule string) error {
+// defaultTaggingClient returns a client for the resource groups tagging API,
+// used to find alarms by tag. The custom endpoint, if any, must serve the
+// tagging API too, as local emulators of AWS do: asking the default endpoint
+// would find the alarms of another service.
+func defaultTaggingClient() (*resourcegroupstaggingapi.ResourceGroupsTaggingAPI, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String(region),
+		Endpoint:    aws.String(endpoint),
+		Credentials: credentials.NewSharedCredentials("", profile),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resourcegroupstaggingapi.New(sess), nil
+}
+
+// pca puts a composite alarm with the given rule. The tags are only applied
+// when the alarm is created; CloudWatch ignores them on updates, so existing
+// alarms are tagged with tagAlarm.
+func pca(ctx context.Context, cw *cloudwatch.CloudWatch, name, rule string, tags map[string]string) error {
 	if verbose {
 		log.Printf("Setting %s to %s", name, rule)
 	}
-	req, _ := cw.PutCompositeAlarmRequest(&cloudwatch.PutCompositeAlarmInput{
+	input := &cloudwatch.PutCompositeAlarmInput{
 		AlarmName: aws.String(name),
 		AlarmRule: aws.String(rule),
-	})
-	addHeaders(req)
-	err := req.Send()
+	}
+	for k, v := range tags {
+		input.Tags = append(input.Tags, &cloudwatch.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(v),
+		})
+	}
+	_, err := cw.PutCompositeAlarmWithContext(ctx, input, requestOptions...)
+	if err != nil {
+		return fmt.Errorf("error putting %q with rule %q: %w", name, rule, apiError(err, name))
+	}
+	return nil
+}
+
+// tagAlarm adds the tags to the alarm, replacing the values of those it
+// has already.
+func tagAlarm(ctx context.Context, cw *cloudwatch.CloudWatch, name, arn string, tags map[string]string) error {
+	if verbose {
+		log.Printf("Tagging %s", name)
+	}
+	input := &cloudwatch.TagResourceInput{
+		ResourceARN: aws.String(arn),
+	}
+	for k, v := range tags {
+		input.Tags = append(input.Tags, &cloudwatch.Tag{
+			Key:   aws.String(k),
+			Value: aws.String(v),
+		})
+	}
+	_, err := cw.TagResourceWithContext(ctx, input, requestOptions...)
 	if err != nil {
-		return fmt.Errorf("error putting %q with rule %q: %v", name, rule, err)
+		return fmt.Errorf("error tagging %q: %w", name, apiError(err, name))
 	}
 	return nil
 }
 
-func pcab(cw *cloudwatch.CloudWatch, name string, constant bool) error {
+func pcab(ctx context.Context, cw *cloudwatch.CloudWatch, name string, constant bool) error {
 	if constant {
-		return pca(cw, name, "TRUE")
+		return pca(ctx, cw, name, "TRUE", nil)
 	}
-	return pca(cw, name, "FALSE")
+	return pca(ctx, cw, name, "FALSE", nil)
 }
 
-func sas(cw *cloudwatch.CloudWatch, name string, constant bool) error {
+func sas(ctx context.Context, cw *cloudwatch.CloudWatch, name string, constant bool) error {
 	if verbose {
 		log.Printf("Setting state of %q to %t", name, constant)
 	}
@@ -79,79 +144,94 @@ func sas(cw *cloudwatch.CloudWatch, name string, constant bool) error {
 	} else {
 		stateValue = cloudwatch.StateValueOk
 	}
-	req, _ := cw.SetAlarmStateRequest(&cloudwatch.SetAlarmStateInput{
+	_, err := cw.SetAlarmStateWithContext(ctx, &cloudwatch.SetAlarmStateInput{
 		AlarmName:       &name,
 		StateValue:      &stateValue,
 		StateReason:     aws.String("8-bit adder test"),
 		StateReasonData: aws.String("{}"),
-	})
-	addHeaders(req)
-	if err := req.Send(); err != nil {
-		return fmt.Errorf("setting alarm state %q to %q: %w", name, stateValue, err)
+	}, requestOptions...)
+	if err != nil {
+		return fmt.Errorf("setting alarm state %q to %q: %w", name, stateValue, apiError(err, name))
 	}
 	return nil
 }
 
-func children(cw *cloudwatch.CloudWatch, parentName string) (childNames []string, err error) {
+// describeComposite calls DescribeAlarms with the given input, following
+// NextToken until all pages are read, and calls fn on each composite alarm.
+// We know the circuits are constructed from composite alarms only, no metric
+// alarms, so we iterate only on the former.
+func describeComposite(ctx context.Context, cw *cloudwatch.CloudWatch, input *cloudwatch.DescribeAlarmsInput, fn func(*cloudwatch.CompositeAlarm)) error {
+	err := cw.DescribeAlarmsPagesWithContext(ctx, input, func(output *cloudwatch.DescribeAlarmsOutput, _ bool) bool {
+		for _, a := range output.CompositeAlarms {
+			fn(a)
+		}
+		return true
+	}, requestOptions...)
+	return apiError(err, aws.StringValueSlice(input.AlarmNames)...)
+}
+
+// describeNamed is like describeComposite, but for the named alarms, which
+// can be more than a single DescribeAlarms call accepts.
+func describeNamed(ctx context.Context, cw *cloudwatch.CloudWatch, alarmNames []string, fn func(*cloudwatch.CompositeAlarm)) error {
+	for _, c := range chunk(alarmNames, maxAlarmNames) {
+		input := &cloudwatch.DescribeAlarmsInput{
+			AlarmNames: aws.StringSlice(c),
+			AlarmTypes: []*string{aws.String(cloudwatch.AlarmTypeCompositeAlarm)},
+		}
+		if err := describeComposite(ctx, cw, input, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func children(ctx context.Context, cw *cloudwatch.CloudWatch, parentName string) (childNames []string, err error) {
 	if verbose {
 		log.Printf("Finding children of: %s", parentName)
 	}
-	req, output := cw.DescribeAlarmsRequest(&cloudwatch.DescribeAlarmsInput{
+	input := &cloudwatch.DescribeAlarmsInput{
 		ChildrenOfAlarmName: aws.String(parentName),
-	})
-	addHeaders(req)
-	if err := req.Send(); err != nil {
-		return nil, err
 	}
-	// We know the circuits are constructed from composite alarms only, no
-	// metric alarms, so we iterate only on the former.
-	for _, child := range output.CompositeAlarms {
+	err = describeComposite(ctx, cw, input, func(child *cloudwatch.CompositeAlarm) {
 		childNames = append(childNames, *child.AlarmName)
-	}
+	})
 	return
 }
 
-func parents(cw *cloudwatch.CloudWatch, childName string) (parentNames []string, err error) {
+func parents(ctx context.Context, cw *cloudwatch.CloudWatch, childName string) (parentNames []string, err error) {
 	if verbose {
 		log.Printf("Finding parents of: %s", childName)
 	}
-	req, output := cw.DescribeAlarmsRequest(&cloudwatch.DescribeAlarmsInput{
+	input := &cloudwatch.DescribeAlarmsInput{
 		ParentsOfAlarmName: aws.String(childName),
-	})
-	addHeaders(req)
-	if err := req.Send(); err != nil {
-		return nil, err
 	}
-	// We know the circuits are constructed from composite alarms only, no
-	// metric alarms, so we iterate only on the former.
-	for _, parent := range output.CompositeAlarms {
+	err = describeComposite(ctx, cw, input, func(parent *cloudwatch.CompositeAlarm) {
 		parentNames = append(parentNames, *parent.AlarmName)
-	}
+	})
 	return
 }
 
-func da(cw *cloudwatch.CloudWatch, name string) error {
+func da(ctx context.Context, cw *cloudwatch.CloudWatch, name string) error {
 	if verbose {
 		log.Printf("Deleting %s", name)
 	}
-	req, _ := cw.DeleteAlarmsRequest(&cloudwatch.DeleteAlarmsInput{
+	_, err := cw.DeleteAlarmsWithContext(ctx, &cloudwatch.DeleteAlarmsInput{
 		AlarmNames: []*string{aws.String(name)},
-	})
-	addHeaders(req)
-	return req.Send()
+	}, requestOptions...)
+	return apiError(err, name)
 }
 
-func daRecursive(cw *cloudwatch.CloudWatch, name string) error {
-	parentNames, err := parents(cw, name)
+func daRecursive(ctx context.Context, cw *cloudwatch.CloudWatch, name string) error {
+	parentNames, err := parents(ctx, cw, name)
 	if err != nil {
 		return err
 	}
 	for _, pn := range parentNames {
-		if err := daRecursive(cw, pn); err != nil {
+		if err := daRecursive(ctx, cw, pn); err != nil {
 			return err
 		}
 	}
-	if err := da(cw, name); err != nil {
+	if err := da(ctx, cw, name); err != nil {
 		return err
 	}
 	return nil
@@ -160,51 +240,67 @@ func daRecursive(cw *cloudwatch.CloudWatch, name string) error {
 // describeStates fetches the state value for each composite alarm in the
 // input.  It uses the same order in the output as specified in the input
 // (something which DescribeAlarms does not do, and I was expect to).
-func describeStates(cw *cloudwatch.CloudWatch, alarmNames []string) (states []string, err error) {
-	input := &cloudwatch.DescribeAlarmsInput{
-		AlarmTypes: []*string{
-			aws.String(cloudwatch.AlarmTypeCompositeAlarm),
-		},
-	}
-	for _, an := range alarmNames {
-		input.AlarmNames = append(input.AlarmNames, aws.String(an))
-	}
-	req, output := cw.DescribeAlarmsRequest(input)
-	addHeaders(req)
-	if err := req.Send(); err != nil {
+func describeStates(ctx context.Context, cw *cloudwatch.CloudWatch, alarmNames []string) (states []string, err error) {
+	alarmStates, err := describeAlarmStates(ctx, cw, alarmNames)
+	if err != nil {
 		return nil, err
 	}
-	if got, want := len(output.CompositeAlarms), len(alarmNames); got != want {
-		return nil, fmt.Errorf("got %d composite alarms, want %d", got, want)
-	}
-	m := make(map[string]string)
-	for _, a := range output.CompositeAlarms {
-		m[*a.AlarmName] = *a.StateValue
-	}
 	states = make([]string, len(alarmNames))
-	for i, an := range alarmNames {
-		states[i] = m[an]
+	for i, s := range alarmStates {
+		states[i] = s.value
 	}
 	return states, nil
 }
 
 func main() {
-	flag.StringVar(&profile, "profile", "computer", "the AWS profile to use for credentials")
-	flag.StringVar(&region, "region", "eu-west-1", "the AWS region to create/use alarms in")
-	flag.StringVar(&endpoint, "endpoint", "", "the custom `endpoint` if you need to override the default")
-	flag.StringVar(&headers, "headers", "", "additional `headers` if required, in the form k1=v1,k2=v2")
-	name := flag.String("name", "computer", "the `name` of the circuit")
-	build := flag.Bool("build", false, "whether the circuit must be built")
-	visualize := flag.Bool("visualize", false, "whether the circuit should be printed in dot format")
-	exercise := flag.Bool("exercise", false, "exercise the circuit with one random addition")
-	remove := flag.Bool("remove", false, "remove the circuit (all alarms that are part of the circuit)")
-	flag.BoolVar(&verbose, "verbose", false, "log diagnostic messages")
-	seed := flag.Int64("seed", time.Now().Unix(), "seed for random numbers for exercise - only for reproducibility")
-	listRegions := flag.Bool("list-regions", false, "lists the available regions")
-	flag.Parse()
+	if path, err := defaultConfigPath(); err == nil {
+		if cfg, err = loadConfig(path); err != nil {
+			fatal(err)
+		}
+	}
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "help", "-h", "-help", "--help":
+		if len(os.Args) > 2 {
+			if c := findCommand(os.Args[2]); c != nil {
+				fs, _, _ := c.flagSet()
+				fs.SetOutput(os.Stdout)
+				fs.Usage()
+				return
+			}
+		}
+		usage(os.Stdout)
+		return
+	}
+	c := findCommand(os.Args[1])
+	if c == nil {
+		fmt.Fprintf(os.Stderr, "cac: unknown command %q\n\n", os.Args[1])
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+	fs, run, timeout := c.flagSet()
+	_ = fs.Parse(os.Args[2:])
+	if outputFormat != "text" && outputFormat != "json" {
+		fmt.Fprintf(os.Stderr, "cac %s: unknown -output %q, want text or json\n", c.name, outputFormat)
+		os.Exit(2)
+	}
+	cfg.applyCircuit(fs)
+	for key, value := range cfg.headers {
+		if verbose {
+			log.Printf("Will use header %q set to %q for all requests, from the configuration", key, value)
+		}
+		headerMap[key] = value
+	}
 	if headers != "" {
 		for _, pair := range strings.Split(headers, ",") {
 			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) != 2 {
+				fmt.Fprintf(os.Stderr, "cac %s: header %q is not in the form k=v\n", c.name, pair)
+				os.Exit(2)
+			}
 			key := parts[0]
 			value := parts[1]
 			if verbose {
@@ -213,78 +309,32 @@ func main() {
 			headerMap[key] = value
 		}
 	}
-	cw, err := defaultClient()
-	if err != nil {
-		log.Fatal(err)
-	}
-	rca := newRippleCarryAdder(cw, *name)
-	if *build {
-		err = rca.build()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}
-	if *visualize {
-		err = rca.saveGraph(os.Stdout)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}
-	if *exercise {
-		log.Printf("Using seed %d.", *seed)
-		rand.Seed(*seed)
-		a := uint8(rand.Intn(256))
-		b := uint8(rand.Intn(256))
-		log.Printf("Want to add %d and %d and get %d", a, b, a+b)
-		log.Print("Setting inputs.")
-		aRegister := toRegister(a)
-		bRegister := toRegister(b)
-		err := rca.setInputs(aRegister, bRegister)
-		if err != nil {
-			log.Fatalf("Could not set inputs: %v", err)
-		}
-		log.Print("Sleeping.")
-		time.Sleep(time.Second)
-		log.Print("Reading outputs.")
-		attempts := 1
-		log.Printf("  a = %s (%d)", aRegister, a)
-		log.Printf("  b = %s (%d)", bRegister, b)
-	retry:
-		sumRegister, overflow, err := rca.readOutputs()
-		if err != nil {
-			log.Fatalf("Could not read outputs: %v", err)
-		}
-		if overflow {
-			log.Printf("WARNING: The computation overflowed.")
-		}
-		sum := fromRegister(sumRegister)
-		log.Printf("sum = %s (%d)", sumRegister, sum)
-		if sum == a+b {
-			log.Printf("STATUS: Success at attempt %d.", attempts)
-		} else if attempts == 10 {
-			log.Printf("STATUS: Failed!!! Because %d != %d.", sum, a+b)
-		} else {
-			time.Sleep(time.Second)
-			attempts++
-			goto retry
-		}
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
 	}
-	if *remove {
-		if err := rca.remove(); err != nil {
-			log.Fatal(err)
-		}
+	// The first interrupt cancels what's in progress, leaving the chance to
+	// clean up, e.g., to roll back a build. The second one terminates.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		signal.Stop(interrupt)
+		log.Print("Interrupted.")
+		cancel()
+	}()
+	err := run(ctx, fs.Args())
+	var ue usageError
+	if errors.As(err, &ue) {
+		fmt.Fprintf(os.Stderr, "cac %s: %s\n\n", c.name, ue)
+		fs.Usage()
+		os.Exit(2)
 	}
-	if *listRegions {
-		var regions []string
-		for _, r := range endpoints.AwsPartition().Services()[cloudwatch.EndpointsID].Regions() {
-			regions = append(regions, r.ID())
-		}
-		for _, r := range endpoints.AwsCnPartition().Services()[cloudwatch.EndpointsID].Regions() {
-			regions = append(regions, r.ID())
-		}
-		sort.Strings(regions)
-		for _, r := range regions {
-			fmt.Println(r)
-		}
+	if err != nil {
+		fatal(err)
 	}
 }
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// outputFormat is the format of the results of commands, text for people or
+// json for scripts, set with the -output flag. Logs go to standard error in
+// either case, and are terser with json.
+var outputFormat = "text"
+
+func jsonOutput() bool {
+	return outputFormat == "json"
+}
+
+// printJSON writes v as indented JSON to standard output.
+func printJSON(v interface{}) error {
+	return encodeJSON(os.Stdout, v)
+}
+
+func encodeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// errorJSON returns the message of err, for the error field of the output of
+// a command that failed, or the empty string, omitted, if err is nil.
+func errorJSON(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+type additionJSON struct {
+	A         uint8   `json:"a"`
+	B         uint8   `json:"b"`
+	Sum       uint8   `json:"sum"`
+	Overflow  bool    `json:"overflow"`
+	OK        bool    `json:"ok"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+func newAdditionJSON(r exerciseResult) additionJSON {
+	return additionJSON{
+		A:         r.a,
+		B:         r.b,
+		Sum:       fromRegister(r.sum),
+		Overflow:  r.overflow,
+		OK:        r.ok,
+		LatencyMS: milliseconds(r.latency),
+	}
+}
+
+type summaryJSON struct {
+	Runs      int     `json:"runs"`
+	Successes int     `json:"successes"`
+	MinMS     float64 `json:"min_ms"`
+	AvgMS     float64 `json:"avg_ms"`
+	MaxMS     float64 `json:"max_ms"`
+}
+
+func newSummaryJSON(s exerciseSummary) summaryJSON {
+	j := summaryJSON{
+		Runs:      s.runs,
+		Successes: s.successes,
+		MinMS:     milliseconds(s.min),
+		MaxMS:     milliseconds(s.max),
+	}
+	if s.runs > 0 {
+		j.AvgMS = milliseconds(s.total / time.Duration(s.runs))
+	}
+	return j
+}
+
+type transitionJSON struct {
+	Alarm    string    `json:"alarm"`
+	At       time.Time `json:"at"`
+	OffsetMS float64   `json:"offset_ms"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+}
+
+// newTimelineJSON returns the transitions, with their offsets from origin.
+func newTimelineJSON(transitions []transition, origin time.Time) []transitionJSON {
+	out := []transitionJSON{}
+	for _, t := range transitions {
+		out = append(out, transitionJSON{
+			Alarm:    t.alarm,
+			At:       t.at.UTC(),
+			OffsetMS: milliseconds(t.at.Sub(origin)),
+			From:     t.from,
+			To:       t.to,
+		})
+	}
+	return out
+}
+
+type mismatchJSON struct {
+	A             uint8 `json:"a"`
+	B             uint8 `json:"b"`
+	Want          uint8 `json:"want"`
+	Sum           uint8 `json:"sum"`
+	Overflow      bool  `json:"overflow"`
+	WrongOverflow bool  `json:"wrong_overflow"`
+}
+
+type verificationJSON struct {
+	Checked    int            `json:"checked"`
+	ElapsedMS  float64        `json:"elapsed_ms"`
+	Mismatches []mismatchJSON `json:"mismatches"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// newVerificationJSON returns the results of a verification, which stopped
+// early if err is not nil.
+func newVerificationJSON(v verification, err error) verificationJSON {
+	j := verificationJSON{
+		Checked:    v.checked,
+		ElapsedMS:  milliseconds(v.elapsed),
+		Mismatches: []mismatchJSON{},
+		Error:      errorJSON(err),
+	}
+	for _, m := range v.mismatches {
+		j.Mismatches = append(j.Mismatches, mismatchJSON{
+			A:             m.a,
+			B:             m.b,
+			Want:          m.a + m.b,
+			Sum:           fromRegister(m.sum),
+			Overflow:      m.overflow,
+			WrongOverflow: m.wrongOverflow,
+		})
+	}
+	return j
+}
+
+type settleJSON struct {
+	Output string  `json:"output"`
+	N      int     `json:"n"`
+	P50MS  float64 `json:"p50_ms"`
+	P90MS  float64 `json:"p90_ms"`
+	P99MS  float64 `json:"p99_ms"`
+	MaxMS  float64 `json:"max_ms"`
+}
+
+type benchJSON struct {
+	Runs      int          `json:"runs"`
+	ElapsedMS float64      `json:"elapsed_ms"`
+	Outputs   []settleJSON `json:"outputs"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// newBenchJSON returns the same statistics as writeBenchReport, of the runs
+// before err, if any.
+func newBenchJSON(r benchResults, err error) benchJSON {
+	j := benchJSON{Runs: r.runs, ElapsedMS: milliseconds(r.elapsed), Error: errorJSON(err)}
+	row := func(label string, ds []time.Duration) {
+		s := sortedDurations(ds)
+		o := settleJSON{Output: label, N: len(s)}
+		if len(s) > 0 {
+			o.P50MS = milliseconds(percentile(s, 50))
+			o.P90MS = milliseconds(percentile(s, 90))
+			o.P99MS = milliseconds(percentile(s, 99))
+			o.MaxMS = milliseconds(s[len(s)-1])
+		}
+		j.Outputs = append(j.Outputs, o)
+	}
+	for i := 0; i < 8; i++ {
+		row(fmt.Sprintf("sum%d", i), r.settle[i])
+	}
+	row("overflow", r.settle[8])
+	row("all", r.total)
+	return j
+}
+
+type alarmSpecJSON struct {
+	Name   string   `json:"name"`
+	Role   string   `json:"role"`
+	Rule   string   `json:"rule"`
+	Inputs []string `json:"inputs,omitempty"`
+}
+
+type buildJSON struct {
+	Circuit string            `json:"circuit"`
+	Layers  [][]alarmSpecJSON `json:"layers"`
+
+	// The alarms put, in order, empty for dry runs.
+	Put []string `json:"put"`
+
+	// Why the build failed, if it did.
+	Error string `json:"error,omitempty"`
+}
+
+func newBuildJSON(circuit string, layers [][]alarmSpec, put []string, err error) buildJSON {
+	j := buildJSON{Circuit: circuit, Layers: [][]alarmSpecJSON{}, Put: []string{}, Error: errorJSON(err)}
+	for _, layer := range layers {
+		var l []alarmSpecJSON
+		for _, s := range layer {
+			l = append(l, alarmSpecJSON{Name: s.name, Role: s.role, Rule: s.rule, Inputs: s.inputs})
+		}
+		j.Layers = append(j.Layers, l)
+	}
+	j.Put = append(j.Put, put...)
+	return j
+}
+
+type circuitSummaryJSON struct {
+	Name      string     `json:"name"`
+	Alarms    int        `json:"alarms"`
+	LastBuilt *time.Time `json:"last_built,omitempty"`
+}
+
+func newCircuitsJSON(summaries []circuitSummary) []circuitSummaryJSON {
+	out := []circuitSummaryJSON{}
+	for _, s := range summaries {
+		j := circuitSummaryJSON{Name: s.name, Alarms: len(s.alarms)}
+		if !s.lastBuilt.IsZero() {
+			t := s.lastBuilt.UTC()
+			j.LastBuilt = &t
+		}
+		out = append(out, j)
+	}
+	return out
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdditionJSON(t *testing.T) {
+	r := exerciseResult{
+		operands: operands{a: 200, b: 100},
+		sum:      toRegister(44),
+		overflow: true,
+		latency:  1500 * time.Microsecond,
+		ok:       true,
+	}
+	var b strings.Builder
+	if err := encodeJSON(&b, newAdditionJSON(r)); err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "a": 200,
+  "b": 100,
+  "sum": 44,
+  "overflow": true,
+  "ok": true,
+  "latency_ms": 1.5
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSummaryJSON(t *testing.T) {
+	s := newSummaryJSON(summarize([]exerciseResult{
+		{latency: time.Second, ok: true},
+		{latency: 3 * time.Second},
+	}))
+	if s.Runs != 2 || s.Successes != 1 || s.MinMS != 1000 || s.AvgMS != 2000 || s.MaxMS != 3000 {
+		t.Errorf("got %+v", s)
+	}
+	if s := newSummaryJSON(summarize(nil)); s.AvgMS != 0 {
+		t.Errorf("got %+v for no runs", s)
+	}
+}
+
+func TestVerificationJSON(t *testing.T) {
+	var b strings.Builder
+	if err := encodeJSON(&b, newVerificationJSON(verification{checked: 3}, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"mismatches": []`) {
+		t.Errorf("got %s, want an empty list of mismatches", b.String())
+	}
+	if strings.Contains(b.String(), `"error"`) {
+		t.Errorf("got %s, want no error", b.String())
+	}
+	if v := newVerificationJSON(verification{checked: 3}, context.Canceled); v.Error != "context canceled" {
+		t.Errorf("got error %q, want %q", v.Error, "context canceled")
+	}
+	m, _ := checkAddition(255, 2, toRegister(0), true)
+	v := newVerificationJSON(verification{checked: 1, mismatches: []mismatch{m}}, nil)
+	if got, want := v.Mismatches[0], (mismatchJSON{A: 255, B: 2, Want: 1, Sum: 0, Overflow: true}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestBenchJSON(t *testing.T) {
+	var r benchResults
+	r.record(map[int]time.Duration{0: 10 * time.Millisecond, 8: 30 * time.Millisecond})
+	j := newBenchJSON(r, nil)
+	if got, want := len(j.Outputs), 10; got != want {
+		t.Fatalf("got %d outputs, want %d", got, want)
+	}
+	if o := j.Outputs[0]; o.Output != "sum0" || o.N != 1 || o.MaxMS != 10 {
+		t.Errorf("got %+v for the first sum bit", o)
+	}
+	if o := j.Outputs[1]; o.N != 0 || o.MaxMS != 0 {
+		t.Errorf("got %+v for the second sum bit", o)
+	}
+	if o := j.Outputs[9]; o.Output != "all" || o.MaxMS != 30 {
+		t.Errorf("got %+v for all outputs", o)
+	}
+}
+
+func TestBuildJSON(t *testing.T) {
+	rca := newRippleCarryAdder(nil, "c")
+	layers, err := buildLayers(rca.alarms())
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := newBuildJSON("c", layers, nil, nil)
+	if got, want := len(j.Layers), len(layers); got != want {
+		t.Errorf("got %d layers, want %d", got, want)
+	}
+	if j.Put == nil {
+		t.Error("got nil put alarms, want an empty list")
+	}
+	if s := j.Layers[0][0]; s.Name != "ground:rca:c" || s.Role != roleGround || s.Rule != "FALSE" {
+		t.Errorf("got %+v as the first alarm", s)
+	}
+}
+
+func TestTimelineJSON(t *testing.T) {
+	origin := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	j := newTimelineJSON([]transition{
+		{alarm: "x", at: origin.Add(250 * time.Millisecond), from: "OK", to: "ALARM"},
+	}, origin)
+	if got, want := j[0], (transitionJSON{Alarm: "x", At: origin.Add(250 * time.Millisecond), OffsetMS: 250, From: "OK", To: "ALARM"}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if j := newTimelineJSON(nil, origin); j == nil {
+		t.Error("got nil for no transitions, want an empty list")
+	}
+}
+
+func TestCircuitsJSON(t *testing.T) {
+	built := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	var b strings.Builder
+	err := encodeJSON(&b, newCircuitsJSON([]circuitSummary{
+		{name: "a", alarms: []string{"x", "y"}, lastBuilt: built},
+		{name: "b"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[
+  {
+    "name": "a",
+    "alarms": 2,
+    "last_built": "2020-01-02T03:04:05Z"
+  },
+  {
+    "name": "b",
+    "alarms": 0
+  }
+]
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// ruleRefPattern matches the alarm references in a composite alarm rule. Names
+// are either quoted, as in the rules this tool creates, or bare.
+var ruleRefPattern = regexp.MustCompile(`\b(?:ALARM|OK|INSUFFICIENT_DATA)\(\s*("(?:[^"\\]|\\.)*"|[^()\s]+)\s*\)`)
+
+// ruleRefs returns the names of the alarms referenced by a composite alarm
+// rule, without duplicates, in order of first appearance.
+func ruleRefs(rule string) (names []string) {
+	seen := make(map[string]bool)
+	for _, m := range ruleRefPattern.FindAllStringSubmatch(rule, -1) {
+		name := m[1]
+		if unquoted, err := strconv.Unquote(name); err == nil {
+			name = unquoted
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+// deletionLayers orders the alarms with the given rules (keyed by alarm name)
+// so that no alarm is deleted before the alarms whose rules reference it. The
+// alarms within a layer do not reference each other and can be deleted
+// together. References to alarms not in rules are ignored. Alarms in a
+// reference cycle can't be ordered and end up together in the last layer.
+func deletionLayers(rules map[string]string) (layers [][]string) {
+	// For each alarm, how many other alarms in the set reference it.
+	referrers := make(map[string]int)
+	refs := make(map[string][]string)
+	for name, rule := range rules {
+		referrers[name] += 0
+		for _, ref := range ruleRefs(rule) {
+			if _, ok := rules[ref]; ok && ref != name {
+				refs[name] = append(refs[name], ref)
+				referrers[ref]++
+			}
+		}
+	}
+	for len(referrers) > 0 {
+		var layer []string
+		for name, n := range referrers {
+			if n == 0 {
+				layer = append(layer, name)
+			}
+		}
+		if len(layer) == 0 {
+			for name := range referrers {
+				layer = append(layer, name)
+			}
+		}
+		sort.Strings(layer)
+		for _, name := range layer {
+			delete(referrers, name)
+		}
+		for _, name := range layer {
+			for _, ref := range refs[name] {
+				if _, ok := referrers[ref]; ok {
+					referrers[ref]--
+				}
+			}
+		}
+		layers = append(layers, layer)
+	}
+	return
+}
+
+// chunk splits names in consecutive slices of at most size names.
+func chunk(names []string, size int) (chunks [][]string) {
+	for len(names) > size {
+		chunks = append(chunks, names[:size])
+		names = names[size:]
+	}
+	if len(names) > 0 {
+		chunks = append(chunks, names)
+	}
+	return
+}
+
+// describeRules returns the rules of the named composite alarms, keyed by
+// alarm name. Names of alarms that don't exist are not in the result.
+func describeRules(ctx context.Context, cw *cloudwatch.CloudWatch, names []string) (map[string]string, error) {
+	rules := make(map[string]string)
+	err := describeNamed(ctx, cw, names, func(a *cloudwatch.CompositeAlarm) {
+		rules[*a.AlarmName] = aws.StringValue(a.AlarmRule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// alarmsMatching returns the names of all composite alarms in the account
+// and region whose names match the pattern.
+func alarmsMatching(ctx context.Context, cw *cloudwatch.CloudWatch, pattern *regexp.Regexp) (names []string, err error) {
+	input := &cloudwatch.DescribeAlarmsInput{
+		AlarmTypes: []*string{aws.String(cloudwatch.AlarmTypeCompositeAlarm)},
+	}
+	err = describeComposite(ctx, cw, input, func(a *cloudwatch.CompositeAlarm) {
+		if pattern.MatchString(*a.AlarmName) {
+			names = append(names, *a.AlarmName)
+		}
+	})
+	return
+}
+
+// daBatch deletes the named alarms with a single call, so names must not be
+// more than maxAlarmNames.
+func daBatch(ctx context.Context, cw *cloudwatch.CloudWatch, names []string) error {
+	if verbose {
+		log.Printf("Deleting %d alarms: %q", len(names), names)
+	}
+	_, err := cw.DeleteAlarmsWithContext(ctx, &cloudwatch.DeleteAlarmsInput{
+		AlarmNames: aws.StringSlice(names),
+	}, requestOptions...)
+	return apiError(err, names...)
+}
+
+// removeAlarms deletes the named alarms, parents before children, in batches
+// of up to maxAlarmNames names, running up to workers batches at a time.
+// Names of alarms that don't exist are ignored.
+func removeAlarms(ctx context.Context, cw *cloudwatch.CloudWatch, names []string, workers int) error {
+	if workers < 1 {
+		workers = 1
+	}
+	rules, err := describeRules(ctx, cw, names)
+	if err != nil {
+		return err
+	}
+	for _, layer := range deletionLayers(rules) {
+		var (
+			wg    sync.WaitGroup
+			mu    sync.Mutex
+			first error
+		)
+		sem := make(chan struct{}, workers)
+		for _, batch := range chunk(layer, maxAlarmNames) {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(batch []string) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if err := daBatch(ctx, cw, batch); err != nil {
+					mu.Lock()
+					if first == nil {
+						first = err
+					}
+					mu.Unlock()
+				}
+			}(batch)
+		}
+		wg.Wait()
+		if first != nil {
+			return first
+		}
+	}
+	return nil
+}
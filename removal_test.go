@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRuleRefs(t *testing.T) {
+	for _, test := range []struct {
+		rule string
+		want []string
+	}{
+		{"TRUE", nil},
+		{`ALARM("left") AND ALARM("right")`, []string{"left", "right"}},
+		{`(ALARM("a") OR ALARM("b")) AND NOT (ALARM("a") AND ALARM("b"))`, []string{"a", "b"}},
+		{`ALARM("cout:ha:ha1:fa:adder0:rca:computer") OR OK(bare) OR INSUFFICIENT_DATA( "q\"uote" )`, []string{"cout:ha:ha1:fa:adder0:rca:computer", "bare", `q"uote`}},
+	} {
+		if got := ruleRefs(test.rule); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ruleRefs(%q) = %q, want %q", test.rule, got, test.want)
+		}
+	}
+}
+
+func TestDeletionLayers(t *testing.T) {
+	rules := map[string]string{
+		"left":  "FALSE",
+		"right": "FALSE",
+		"cout":  `ALARM("left") AND ALARM("right")`,
+		"sout":  `(ALARM("left") OR ALARM("right")) AND NOT (ALARM("left") AND ALARM("right"))`,
+		"top":   `ALARM("cout") OR ALARM("elsewhere")`,
+		"loop1": `ALARM("loop2")`,
+		"loop2": `ALARM("loop1")`,
+	}
+	want := [][]string{
+		{"sout", "top"},
+		{"cout"},
+		{"left", "right"},
+		{"loop1", "loop2"},
+	}
+	if got := deletionLayers(rules); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestChunk(t *testing.T) {
+	names := []string{"a", "b", "c", "d", "e"}
+	if got, want := chunk(names, 2), [][]string{{"a", "b"}, {"c", "d"}, {"e"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := chunk(nil, 2); len(got) != 0 {
+		t.Errorf("got %q, want no chunks", got)
+	}
+}
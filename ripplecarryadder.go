@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 )
@@ -30,26 +33,23 @@ func newRippleCarryAdder(cw *cloudwatch.CloudWatch, name string) *rippleCarryAdd
 	return rca
 }
 
-func (rca *rippleCarryAdder) build() error {
-	err := pcab(rca.cw, rca.adderCarryInName(0), false)
-	if err != nil {
-		return err
-	}
+// build puts all the alarms of the circuit through tx, stopping at the first
+// error. Rolling back the alarms put so far is up to the caller.
+func (rca *rippleCarryAdder) build(ctx context.Context, tx *buildTx) error {
+	return tx.build(ctx, rca.alarms())
+}
+
+// alarms describes all the alarms making up the circuit, inputs included.
+func (rca *rippleCarryAdder) alarms() []alarmSpec {
+	specs := []alarmSpec{constantSpec(rca.adderCarryInName(0), false, roleGround)}
 	for i := 0; i < 8; i++ {
-		err = pcab(rca.cw, rca.adderLeftInName(i), false)
-		if err != nil {
-			break
-		}
-		err = pcab(rca.cw, rca.adderRightInName(i), false)
-		if err != nil {
-			break
-		}
-		err = rca.adders[i].build()
-		if err != nil {
-			break
-		}
+		specs = append(specs,
+			constantSpec(rca.adderLeftInName(i), false, roleInput),
+			constantSpec(rca.adderRightInName(i), false, roleInput),
+		)
+		specs = append(specs, rca.adders[i].alarms()...)
 	}
-	return err
+	return specs
 }
 
 func (rca *rippleCarryAdder) adderName(i int) string {
@@ -79,10 +79,10 @@ func (rca *rippleCarryAdder) overflowName() string {
 	return rca.adders[7].coutName()
 }
 
-func (rca *rippleCarryAdder) setInputs(leftIn, rightIn register) error {
+func (rca *rippleCarryAdder) setInputs(ctx context.Context, leftIn, rightIn register) error {
 	var err error
 	for i := 0; i < 8; i++ {
-		err = rca.adders[i].setMainInputs(leftIn[i], rightIn[i])
+		err = rca.adders[i].setMainInputs(ctx, leftIn[i], rightIn[i])
 		if err != nil {
 			break
 		}
@@ -90,13 +90,34 @@ func (rca *rippleCarryAdder) setInputs(leftIn, rightIn register) error {
 	return err
 }
 
-func (rca *rippleCarryAdder) readOutputs() (sum register, overflow bool, err error) {
+// outputNames returns the names of the 8 sum bits, from least significant,
+// and of the overflow bit.
+func (rca *rippleCarryAdder) outputNames() []string {
 	alarmNames := make([]string, 9)
 	for i := 0; i < 8; i++ {
 		alarmNames[i] = rca.soutName(i)
 	}
 	alarmNames[8] = rca.overflowName()
-	states, err := describeStates(rca.cw, alarmNames)
+	return alarmNames
+}
+
+func (rca *rippleCarryAdder) readOutputs(ctx context.Context) (sum register, overflow bool, err error) {
+	return rca.decodeOutputs(describeStates(ctx, rca.cw, rca.outputNames()))
+}
+
+// waitForOutputs is like readOutputs, but waits for the outputs to settle
+// after the inputs were set at the given time.
+func (rca *rippleCarryAdder) waitForOutputs(ctx context.Context, since time.Time, opts waitOptions) (sum register, overflow bool, err error) {
+	return rca.decodeOutputs(waitForOutputs(ctx, rca.cw, rca.outputNames(), since, opts))
+}
+
+// encodeOutputs returns the state values of the outputs, in the order of
+// outputNames, for the given sum and overflow.
+func (rca *rippleCarryAdder) encodeOutputs(sum register, overflow bool) []string {
+	return encodeStates(append(sum[:], overflow)...)
+}
+
+func (rca *rippleCarryAdder) decodeOutputs(states []string, err error) (sum register, overflow bool, _ error) {
 	if err != nil {
 		return sum, false, err
 	}
@@ -107,50 +128,35 @@ func (rca *rippleCarryAdder) readOutputs() (sum register, overflow bool, err err
 	return
 }
 
-// saveGraph writes a graph representing the circuit, readable by xdot as a
-// diagnostic and demonstration tool.
-func (rca *rippleCarryAdder) saveGraph(w io.Writer) error {
-	_, _ = fmt.Fprintln(w, "digraph {")
-	// Stack of names of alarms of which to get the children, in order to
-	// find directed edges. Initially consists of all the 8 output bits and
-	// the overflow output bit.
-	var stack []string
-	for i := 0; i < 8; i++ {
-		stack = append(stack, rca.adders[i].soutName())
+// saveGraph writes a graph representing the circuit in the given format (see
+// graphFormats), e.g., DOT readable by xdot, as a diagnostic and
+// demonstration tool. The graph is built from the circuit model, unless live
+// is true, in which case it is discovered from the deployed alarms, and shows
+// their states.
+func (rca *rippleCarryAdder) saveGraph(ctx context.Context, w io.Writer, live bool, format string) error {
+	write, ok := graphFormats[format]
+	if !ok {
+		return fmt.Errorf("unknown graph format %q, want one of %s", format, strings.Join(graphFormatNames(), ", "))
 	}
-	stack = append(stack, rca.adders[7].coutName())
-	seen := make(map[string]struct{})
-	for len(stack) > 0 {
-		last := len(stack) - 1
-		parentName := stack[last]
-		stack = stack[:last]
-		childNames, err := children(rca.cw, parentName)
-		if err != nil {
-			return err
-		}
-		seen[parentName] = struct{}{}
-		for _, cn := range childNames {
-			if _, ok := seen[cn]; !ok {
-				stack = append(stack, cn)
-			}
-			_, _ = fmt.Fprintf(w, "\t%q -> %q;\n", cn, parentName)
-		}
+	g, err := rca.graph(ctx, live)
+	if err != nil {
+		return err
 	}
-	_, _ = fmt.Fprintln(w, "}")
-	return nil
+	return write(g, w)
 }
 
-func (rca *rippleCarryAdder) remove() error {
-	for i := 0; i < 8; i++ {
-		if err := daRecursive(rca.cw, rca.adders[i].leftIn); err != nil {
-			return err
-		}
-		if err := daRecursive(rca.cw, rca.adders[i].rightIn); err != nil {
-			return err
-		}
-		if err := daRecursive(rca.cw, rca.adders[i].carryIn); err != nil {
-			return err
-		}
+// alarmNames returns the names of all the alarms making up the circuit.
+func (rca *rippleCarryAdder) alarmNames() []string {
+	var names []string
+	for _, s := range rca.alarms() {
+		names = append(names, s.name)
 	}
-	return nil
+	return names
+}
+
+// remove deletes all the alarms of the circuit, as known from its naming
+// scheme. To also find alarms the naming scheme doesn't know about, use
+// removeAlarms with names found by tag or pattern.
+func (rca *rippleCarryAdder) remove(ctx context.Context, workers int) error {
+	return removeAlarms(ctx, rca.cw, rca.alarmNames(), workers)
 }
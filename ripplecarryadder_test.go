@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"testing"
 	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
 )
 
 func TestRippleCarryAdder(t *testing.T) {
@@ -14,24 +19,34 @@ func TestRippleCarryAdder(t *testing.T) {
 	sb := make([]byte, 16)
 	rand.Read(sb)
 	suffix := fmt.Sprintf(":%x", sb)
+	ctx := context.Background()
 	cw, err := defaultClient()
 	if err != nil {
 		t.Fatal(err)
 	}
 	rca := newRippleCarryAdder(cw, "test"+suffix)
-	err = rca.build()
+	err = rca.build(ctx, newBuildTx(cw, rca.name))
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer func() { _ = rca.remove() }()
+	defer func() { _ = rca.remove(ctx, 4) }()
+	// More names than a single DescribeAlarms call accepts.
+	names := append(rca.alarmNames(), rca.alarmNames()...)
+	if states, err := describeStates(ctx, cw, names); err != nil {
+		t.Error(err)
+	} else if len(states) != len(names) {
+		t.Errorf("got %d states, want %d", len(states), len(names))
+	}
 	var a uint8 = 25
 	var b uint8 = 87
-	err = rca.setInputs(toRegister(a), toRegister(b))
+	since := time.Now()
+	err = rca.setInputs(ctx, toRegister(a), toRegister(b))
 	if err != nil {
 		t.Fatal(err)
 	}
-	time.Sleep(evaluationLatency)
-	sum, overflow, err := rca.readOutputs()
+	opts := defaultWaitOptions
+	opts.want = rca.encodeOutputs(toRegister(a+b), uint16(a)+uint16(b) > 255)
+	sum, overflow, err := rca.waitForOutputs(ctx, since, opts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -42,3 +57,132 @@ func TestRippleCarryAdder(t *testing.T) {
 		t.Errorf("a=%d b=%d, got %d, want %d", a, b, got, want)
 	}
 }
+
+func TestRippleCarryAdderRollback(t *testing.T) {
+	if profile == "" {
+		t.Skip("Supply test profile with -profile to run this test.")
+	}
+	sb := make([]byte, 16)
+	rand.Read(sb)
+	suffix := fmt.Sprintf(":%x", sb)
+	ctx := context.Background()
+	cw, err := defaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rca := newRippleCarryAdder(cw, "test"+suffix)
+	tx := newBuildTx(cw, rca.name)
+	err = tx.build(ctx, []alarmSpec{
+		constantSpec(rca.adderCarryInName(0), false, roleGround),
+		constantSpec(rca.adderLeftInName(0), false, roleInput),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interrupted, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := rca.build(interrupted, tx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if err := tx.rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := describeStates(ctx, cw, tx.put); err == nil {
+		t.Errorf("got no error describing rolled back alarms %q", tx.put)
+	}
+}
+
+func TestRippleCarryAdderRollbackKeepsExisting(t *testing.T) {
+	if profile == "" {
+		t.Skip("Supply test profile with -profile to run this test.")
+	}
+	sb := make([]byte, 16)
+	rand.Read(sb)
+	suffix := fmt.Sprintf(":%x", sb)
+	ctx := context.Background()
+	cw, err := defaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rca := newRippleCarryAdder(cw, "test"+suffix)
+	existing := constantSpec(rca.adderCarryInName(0), false, roleGround)
+	if err := newBuildTx(cw, rca.name).build(ctx, []alarmSpec{existing}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = da(ctx, cw, existing.name) }()
+	tx := newBuildTx(cw, rca.name)
+	created := constantSpec(rca.adderLeftInName(0), false, roleInput)
+	if err := tx.build(ctx, []alarmSpec{existing, created}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := describeRules(ctx, cw, []string{existing.name, created.name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rules[existing.name]; !ok {
+		t.Errorf("rollback deleted %q, which existed before the build", existing.name)
+	}
+	if _, ok := rules[created.name]; ok {
+		t.Errorf("rollback kept %q, which the build created", created.name)
+	}
+}
+
+func TestBuildTxCreated(t *testing.T) {
+	tx := newBuildTx(nil, "test")
+	tx.put = []string{"a", "b", "c"}
+	tx.existing = map[string]string{"b": "arn:b"}
+	if got, want := fmt.Sprint(tx.created()), "[a c]"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestRippleCarryAdderRebuildTagsExisting(t *testing.T) {
+	if profile == "" {
+		t.Skip("Supply test profile with -profile to run this test.")
+	}
+	sb := make([]byte, 16)
+	rand.Read(sb)
+	suffix := fmt.Sprintf(":%x", sb)
+	ctx := context.Background()
+	cw, err := defaultClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rca := newRippleCarryAdder(cw, "test"+suffix)
+	name := rca.adderCarryInName(0)
+	// As put before alarms were tagged.
+	if err := pcab(ctx, cw, name, false); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = da(ctx, cw, name) }()
+	tx := newBuildTx(cw, rca.name)
+	if err := tx.build(ctx, []alarmSpec{constantSpec(name, false, roleGround)}); err != nil {
+		t.Fatal(err)
+	}
+	output, err := cw.ListTagsForResourceWithContext(ctx, &cloudwatch.ListTagsForResourceInput{
+		ResourceARN: aws.String(tx.existing[name]),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var circuit string
+	for _, tag := range output.Tags {
+		if *tag.Key == tagCircuit {
+			circuit = *tag.Value
+		}
+	}
+	if circuit != rca.name {
+		t.Errorf("got circuit tag %q, want %q", circuit, rca.name)
+	}
+}
+
+func TestRippleCarryAdderEncodeOutputs(t *testing.T) {
+	rca := newRippleCarryAdder(nil, "test")
+	sum, overflow, err := rca.decodeOutputs(rca.encodeOutputs(toRegister(0xa5), true), nil)
+	if err != nil || sum != toRegister(0xa5) || !overflow {
+		t.Errorf("got %v, %t, %v, want %v, true", sum, overflow, err, toRegister(0xa5))
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// alarmSpec describes an alarm of a circuit: its name, its rule, the names of
+// the alarms its rule references, and its role within the circuit.
+type alarmSpec struct {
+	name   string
+	rule   string
+	inputs []string
+	role   string
+}
+
+func constantSpec(name string, constant bool, role string) alarmSpec {
+	rule := "FALSE"
+	if constant {
+		rule = "TRUE"
+	}
+	return alarmSpec{name: name, rule: rule, role: role}
+}
+
+// buildLayers orders the specs so that each alarm comes after the alarms its
+// rule references. The alarms within a layer don't depend on each other and
+// can be put concurrently. Inputs not among the specs are assumed to exist
+// already. An error is returned if the specs have a dependency cycle.
+func buildLayers(specs []alarmSpec) ([][]alarmSpec, error) {
+	byName := make(map[string]alarmSpec)
+	for _, s := range specs {
+		byName[s.name] = s
+	}
+	// For each alarm, how many of its inputs are still to be put.
+	pending := make(map[string]int)
+	dependents := make(map[string][]string)
+	for _, s := range specs {
+		pending[s.name] += 0
+		for _, in := range s.inputs {
+			if _, ok := byName[in]; ok {
+				pending[s.name]++
+				dependents[in] = append(dependents[in], s.name)
+			}
+		}
+	}
+	var layers [][]alarmSpec
+	for len(pending) > 0 {
+		var names []string
+		for name, n := range pending {
+			if n == 0 {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("dependency cycle among %d alarms", len(pending))
+		}
+		sort.Strings(names)
+		layer := make([]alarmSpec, len(names))
+		for i, name := range names {
+			delete(pending, name)
+			layer[i] = byName[name]
+		}
+		for _, name := range names {
+			for _, d := range dependents[name] {
+				pending[d]--
+			}
+		}
+		layers = append(layers, layer)
+	}
+	return layers, nil
+}
+
+// tokenBucket limits the rate of API calls. A nil bucket doesn't limit.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a bucket allowing rate calls per second on average,
+// and up to burst calls at once. It returns nil if rate is not positive.
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// take blocks until a token is available and takes it, or until ctx is done,
+// returning its error.
+func (b *tokenBucket) take(ctx context.Context) error {
+	if b == nil {
+		return ctx.Err()
+	}
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	// Going negative reserves a future token for this caller.
+	b.tokens--
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+	if err := sleep(ctx, wait); err != nil {
+		// Give the reserved token back.
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// sleep waits for d, or until ctx is done, returning its error.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func isThrottling(err error) bool {
+	var te *throttledError
+	return errors.As(apiError(err), &te)
+}
+
+// Parameters of the retries of throttled calls.
+var (
+	throttleRetries   = 8
+	throttleBaseDelay = 200 * time.Millisecond
+	throttleMaxDelay  = 10 * time.Second
+)
+
+// retryThrottled calls op until it succeeds, fails with an error other than
+// throttling, or has been throttled too many times. Between attempts it
+// sleeps for a random duration up to an exponentially growing delay (full
+// jitter), so that concurrent workers don't retry in lockstep. It stops
+// sleeping when ctx is done, returning the last error.
+func retryThrottled(ctx context.Context, op func() error) error {
+	delay := throttleBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || !isThrottling(err) || attempt == throttleRetries {
+			return err
+		}
+		if sleep(ctx, time.Duration(rand.Int63n(int64(delay)+1))) != nil {
+			return err
+		}
+		delay *= 2
+		if delay > throttleMaxDelay {
+			delay = throttleMaxDelay
+		}
+	}
+}
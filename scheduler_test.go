@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestBuildLayers(t *testing.T) {
+	specs := newRippleCarryAdder(nil, "test").alarms()
+	layers, err := buildLayers(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Inputs, then the first half-adders of all adders, then two layers per
+	// adder as the carry ripples.
+	if got, want := len(layers), 18; got != want {
+		t.Errorf("got %d layers, want %d", got, want)
+	}
+	layerOf := make(map[string]int)
+	count := 0
+	for i, layer := range layers {
+		for _, s := range layer {
+			layerOf[s.name] = i
+			count++
+		}
+	}
+	if count != len(specs) {
+		t.Errorf("got %d alarms in layers, want %d", count, len(specs))
+	}
+	for _, s := range specs {
+		for _, in := range s.inputs {
+			if layerOf[in] >= layerOf[s.name] {
+				t.Errorf("%q in layer %d, but its input %q in layer %d", s.name, layerOf[s.name], in, layerOf[in])
+			}
+		}
+	}
+}
+
+func TestBuildLayersCycle(t *testing.T) {
+	specs := []alarmSpec{
+		{name: "a", inputs: []string{"b"}},
+		{name: "b", inputs: []string{"a"}},
+		{name: "c", inputs: []string{"outside"}},
+	}
+	if _, err := buildLayers(specs); err == nil {
+		t.Error("got no error for dependency cycle")
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	if newTokenBucket(0, 1) != nil {
+		t.Error("got a limiting bucket for rate 0")
+	}
+	ctx := context.Background()
+	b := newTokenBucket(100, 2)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := b.take(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first 2 tokens are available immediately, the other 4 come at
+	// 10ms intervals.
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("took 6 tokens in %v, want about 40ms", elapsed)
+	}
+	// Waiting for a token stops when the context is done.
+	b = newTokenBucket(0.001, 1)
+	if err := b.take(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	start = time.Now()
+	if err := b.take(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v to give up", elapsed)
+	}
+}
+
+func TestRetryThrottled(t *testing.T) {
+	defer func(d time.Duration) { throttleBaseDelay = d }(throttleBaseDelay)
+	throttleBaseDelay = time.Millisecond
+	ctx := context.Background()
+	throttled := awserr.New("Throttling", "Rate exceeded", nil)
+	calls := 0
+	err := retryThrottled(ctx, func() error {
+		calls++
+		if calls < 3 {
+			return throttled
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("got %v after %d calls, want success after 3", err, calls)
+	}
+	calls = 0
+	err = retryThrottled(ctx, func() error {
+		calls++
+		return throttled
+	})
+	if err != throttled || calls != throttleRetries {
+		t.Errorf("got %v after %d calls, want throttling after %d", err, calls, throttleRetries)
+	}
+	calls = 0
+	other := errors.New("other")
+	err = retryThrottled(ctx, func() error {
+		calls++
+		return other
+	})
+	if err != other || calls != 1 {
+		t.Errorf("got %v after %d calls, want other error after 1", err, calls)
+	}
+	// No more retries once the context is done.
+	calls = 0
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = retryThrottled(cancelled, func() error {
+		calls++
+		return throttled
+	})
+	if err != throttled || calls != 1 {
+		t.Errorf("got %v after %d calls, want throttling after 1", err, calls)
+	}
+}
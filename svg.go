@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// Dimensions of the schematic, in pixels.
+const (
+	svgMargin     = 40
+	svgGateWidth  = 40
+	svgGateHeight = 30
+	svgColumn     = 150 // Distance between the left sides of gates in adjacent layers.
+	svgRow        = 60  // Minimum distance between the tops of gates in a layer.
+	svgTrack      = 4   // Distance between vertical wires in a channel.
+)
+
+type svgPoint struct {
+	x, y int
+}
+
+// svgLayout places each node of a graph in a layer, from left to right in
+// the direction signals flow, and gives it coordinates.
+type svgLayout struct {
+	layers [][]string
+	at     map[string]svgPoint
+}
+
+// layout assigns each node to the layer after the last of its inputs. The
+// nodes in the top row, i.e., the inputs, go first, in order, and those in
+// the bottom row, i.e., the outputs, go last, in order. The other nodes are
+// placed close to their inputs.
+func (g *circuitGraph) layout() *svgLayout {
+	inputs := make(map[string][]string)
+	for _, e := range g.sortedEdges() {
+		inputs[e.to] = append(inputs[e.to], e.from)
+	}
+	first := make(map[string]int)
+	last := make(map[string]int)
+	for _, row := range g.rows {
+		for i, name := range row.names {
+			if row.rank == "min" {
+				first[name] = i + 1
+			} else {
+				last[name] = i + 1
+			}
+		}
+	}
+	depths := make(map[string]int)
+	var depth func(name string) int
+	depth = func(name string) int {
+		if d, ok := depths[name]; ok {
+			return d
+		}
+		d := 0
+		if first[name] == 0 {
+			for _, in := range inputs[name] {
+				if di := depth(in) + 1; di > d {
+					d = di
+				}
+			}
+		}
+		depths[name] = d
+		return d
+	}
+	deepest := 0
+	for name := range g.nodes {
+		if d := depth(name); d > deepest {
+			deepest = d
+		}
+	}
+	l := &svgLayout{layers: make([][]string, deepest+1), at: make(map[string]svgPoint)}
+	for _, n := range g.sortedNodes() {
+		d := depths[n.name]
+		if last[n.name] > 0 {
+			d = deepest
+		}
+		l.layers[d] = append(l.layers[d], n.name)
+	}
+	for i, layer := range l.layers {
+		// Where each node would like to be, level with its inputs.
+		want := make(map[string]int)
+		for _, name := range layer {
+			if len(inputs[name]) == 0 {
+				continue
+			}
+			sum := 0
+			for _, in := range inputs[name] {
+				sum += l.at[in].y
+			}
+			want[name] = sum / len(inputs[name])
+		}
+		sort.SliceStable(layer, func(i, j int) bool {
+			a, b := layer[i], layer[j]
+			if ra, rb := first[a]+last[a], first[b]+last[b]; ra > 0 || rb > 0 {
+				// Rows come in order, and before the rest.
+				return ra > 0 && (rb == 0 || ra < rb)
+			}
+			return want[a] < want[b]
+		})
+		y := svgMargin - svgRow
+		for _, name := range layer {
+			y += svgRow
+			if want[name] > y {
+				y = want[name]
+			}
+			l.at[name] = svgPoint{x: svgMargin + i*svgColumn, y: y}
+		}
+	}
+	return l
+}
+
+func (l *svgLayout) size() (width, height int) {
+	for _, p := range l.at {
+		if p.x > width {
+			width = p.x
+		}
+		if p.y > height {
+			height = p.y
+		}
+	}
+	// Room for the last gate, and for its label.
+	return width + svgGateWidth + 2*svgMargin + svgColumn, height + svgGateHeight + 2*svgMargin
+}
+
+// svgWireColors are the colors of wires by the state of the alarm driving
+// them; black otherwise.
+var svgWireColors = map[string]string{
+	cloudwatch.StateValueAlarm: "#d93025",
+}
+
+// svgGateSymbols are the outlines of the gates, relative to their top left
+// corner, as SVG path data.
+var svgGateSymbols = map[string]string{
+	"AND":    "M0,0 h20 a15,15 0 0 1 0,30 h-20 z",
+	"OR":     "M0,0 q25,0 40,15 q-15,15 -40,15 q10,-15 0,-30 z",
+	"XOR":    "M6,0 q25,0 34,15 q-9,15 -34,15 q10,-15 0,-30 z M0,0 q10,15 0,30",
+	"input":  "M0,5 h30 l10,10 l-10,10 h-30 z",
+	"ground": "M10,15 h20 M10,22 h20 M15,29 h10 M20,0 v15",
+}
+
+// writeSVG writes a schematic of the graph in SVG, with a symbol per gate,
+// filled by state if known, and orthogonal wires, colored by the state of
+// the gate driving them. It needs no external tool to lay out the graph,
+// unlike DOT. Gates and wires carry the names of the alarms in data-alarm
+// and data-from attributes, for scripts to update them.
+func (g *circuitGraph) writeSVG(w io.Writer) error {
+	l := g.layout()
+	width, height := l.size()
+	_, _ = fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"monospace\" font-size=\"10\">\n", width, height, width, height)
+	_, _ = fmt.Fprintln(w, "<rect width=\"100%\" height=\"100%\" fill=\"white\"/>")
+	// Wires go first, so that gates are drawn over them. Each source has its
+	// own track in the channel to the right of its layer, so that vertical
+	// wires don't overlap.
+	tracks := make(map[string]int)
+	for _, layer := range l.layers {
+		for i, name := range layer {
+			tracks[name] = i
+		}
+	}
+	inputs := make(map[string][]string)
+	for _, e := range g.sortedEdges() {
+		inputs[e.to] = append(inputs[e.to], e.from)
+	}
+	_, _ = fmt.Fprintln(w, "<g fill=\"none\" stroke=\"black\">")
+	for _, e := range g.sortedEdges() {
+		from, to := l.at[e.from], l.at[e.to]
+		pins := inputs[e.to]
+		pin := 0
+		for i, in := range pins {
+			if in == e.from {
+				pin = i
+			}
+		}
+		x1, y1 := from.x+svgGateWidth, from.y+svgGateHeight/2
+		x2, y2 := to.x, to.y+svgGateHeight*(pin+1)/(len(pins)+1)
+		xm := x1 + 10 + svgTrack*tracks[e.from]%(svgColumn-svgGateWidth-20)
+		stroke := ""
+		if color, ok := svgWireColors[g.nodes[e.from].state]; ok {
+			stroke = fmt.Sprintf(" stroke=%q", color)
+		}
+		_, _ = fmt.Fprintf(w, "<path d=\"M%d,%d H%d V%d H%d\" data-from=\"%s\"%s/>\n", x1, y1, xm, y2, x2, xmlEscape(e.from), stroke)
+	}
+	_, _ = fmt.Fprintln(w, "</g>")
+	for _, n := range g.sortedNodes() {
+		p := l.at[n.name]
+		fill := "white"
+		if color, ok := stateColors[n.state]; ok {
+			fill = color
+		}
+		symbol, ok := svgGateSymbols[n.gate]
+		if !ok {
+			symbol = fmt.Sprintf("M0,0 h%d v%d h-%d z", svgGateWidth, svgGateHeight, svgGateWidth)
+		}
+		_, _ = fmt.Fprintf(w, "<g transform=\"translate(%d,%d)\" data-alarm=\"%s\">\n", p.x, p.y, xmlEscape(n.name))
+		_, _ = fmt.Fprintf(w, "<title>%s</title>\n", xmlEscape(g.label(n)))
+		_, _ = fmt.Fprintf(w, "<path d=\"%s\" fill=\"%s\" stroke=\"black\"/>\n", symbol, fill)
+		_, _ = fmt.Fprintf(w, "<text y=\"%d\">%s</text>\n", svgGateHeight+12, xmlEscape(strings.TrimSuffix(n.name, g.suffix)))
+		_, _ = fmt.Fprintln(w, "</g>")
+	}
+	_, err := fmt.Fprintln(w, "</svg>")
+	return err
+}
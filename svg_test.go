@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestLayout(t *testing.T) {
+	g, err := newRippleCarryAdder(nil, "c").graph(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := g.layout()
+	// The inputs and ground, 16 layers of gates in the carry chain, and the
+	// outputs.
+	if got, want := len(l.layers), 18; got != want {
+		t.Fatalf("got %d layers, want %d", got, want)
+	}
+	for _, row := range g.rows {
+		layer := l.layers[0]
+		if row.rank == "max" {
+			layer = l.layers[len(l.layers)-1]
+		}
+		if got, want := strings.Join(layer[:len(row.names)], " "), strings.Join(row.names, " "); got != want {
+			t.Errorf("got layer starting with %s, want %s", got, want)
+		}
+	}
+	for i, layer := range l.layers {
+		for j := 1; j < len(layer); j++ {
+			above, below := l.at[layer[j-1]], l.at[layer[j]]
+			if below.x != above.x || below.y-above.y < svgRow {
+				t.Errorf("layer %d: %q at %v too close to %q at %v", i, layer[j], below, layer[j-1], above)
+			}
+		}
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	g, err := newRippleCarryAdder(nil, "c").graph(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.node("lin0:rca:c").state = "ALARM"
+	var b strings.Builder
+	if err := g.writeSVG(&b); err != nil {
+		t.Fatal(err)
+	}
+	type path struct {
+		D    string `xml:"d,attr"`
+		Fill string `xml:"fill,attr"`
+	}
+	var doc struct {
+		Groups []struct {
+			Paths []path `xml:"path"`
+			Text  string `xml:"text"`
+		} `xml:"g"`
+	}
+	if err := xml.Unmarshal([]byte(b.String()), &doc); err != nil {
+		t.Fatal(err)
+	}
+	// A group of wires, then a group per gate.
+	if got, want := len(doc.Groups), 1+len(g.nodes); got != want {
+		t.Fatalf("got %d groups, want %d", got, want)
+	}
+	if got, want := len(doc.Groups[0].Paths), len(g.edges); got != want {
+		t.Errorf("got %d wires, want %d", got, want)
+	}
+	for _, gate := range doc.Groups[1:] {
+		if gate.Text == "lin0" && gate.Paths[0].Fill != stateColors["ALARM"] {
+			t.Errorf("got fill %q for lin0, want %q", gate.Paths[0].Fill, stateColors["ALARM"])
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// transition is a change of state of an alarm, as recorded in its history.
+type transition struct {
+	alarm    string
+	at       time.Time
+	from, to string
+}
+
+// parseHistoryItem extracts the transition from a StateUpdate history item,
+// whose data is of the form
+//
+//	{"oldState": {"stateValue": "OK", ...}, "newState": {"stateValue": "ALARM", ...}, ...}
+func parseHistoryItem(item *cloudwatch.AlarmHistoryItem) (transition, error) {
+	var data struct {
+		OldState struct {
+			StateValue string `json:"stateValue"`
+		} `json:"oldState"`
+		NewState struct {
+			StateValue string `json:"stateValue"`
+		} `json:"newState"`
+	}
+	t := transition{
+		alarm: aws.StringValue(item.AlarmName),
+		at:    aws.TimeValue(item.Timestamp),
+	}
+	if err := json.Unmarshal([]byte(aws.StringValue(item.HistoryData)), &data); err != nil {
+		return t, fmt.Errorf("parsing history data of %q: %w", t.alarm, err)
+	}
+	t.from = data.OldState.StateValue
+	t.to = data.NewState.StateValue
+	return t, nil
+}
+
+// alarmHistory returns the state transitions of the named alarm in the given
+// time range, oldest first.
+func alarmHistory(ctx context.Context, cw *cloudwatch.CloudWatch, name string, since, until time.Time) (transitions []transition, err error) {
+	input := &cloudwatch.DescribeAlarmHistoryInput{
+		AlarmName:       aws.String(name),
+		AlarmTypes:      []*string{aws.String(cloudwatch.AlarmTypeCompositeAlarm)},
+		HistoryItemType: aws.String(cloudwatch.HistoryItemTypeStateUpdate),
+		StartDate:       aws.Time(since),
+		EndDate:         aws.Time(until),
+		ScanBy:          aws.String(cloudwatch.ScanByTimestampAscending),
+	}
+	var perr error
+	err = cw.DescribeAlarmHistoryPagesWithContext(ctx, input, func(output *cloudwatch.DescribeAlarmHistoryOutput, _ bool) bool {
+		for _, item := range output.AlarmHistoryItems {
+			t, err := parseHistoryItem(item)
+			if err != nil {
+				perr = err
+				return false
+			}
+			transitions = append(transitions, t)
+		}
+		return true
+	}, requestOptions...)
+	if err != nil {
+		return nil, apiError(err, name)
+	}
+	return transitions, perr
+}
+
+// timeline returns the transitions of all the alarms of the circuit in the
+// given time range, ordered by time, fetching the histories of up to workers
+// alarms at a time.
+func (rca *rippleCarryAdder) timeline(ctx context.Context, since, until time.Time, workers int) ([]transition, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	var (
+		wg          sync.WaitGroup
+		mu          sync.Mutex
+		transitions []transition
+		first       error
+	)
+	sem := make(chan struct{}, workers)
+	for _, name := range rca.alarmNames() {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			ts, err := alarmHistory(ctx, rca.cw, name, since, until)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if first == nil {
+					first = err
+				}
+				return
+			}
+			transitions = append(transitions, ts...)
+		}(name)
+	}
+	wg.Wait()
+	if first != nil {
+		return nil, first
+	}
+	sortTransitions(transitions)
+	return transitions, nil
+}
+
+func sortTransitions(transitions []transition) {
+	sort.SliceStable(transitions, func(i, j int) bool {
+		if !transitions[i].at.Equal(transitions[j].at) {
+			return transitions[i].at.Before(transitions[j].at)
+		}
+		return transitions[i].alarm < transitions[j].alarm
+	})
+}
+
+// writeTimeline writes the transitions, with their times relative to since,
+// and alarm names shortened by dropping the suffix common to all the alarms
+// of the circuit.
+func (rca *rippleCarryAdder) writeTimeline(w io.Writer, transitions []transition, since time.Time) error {
+	suffix := ":rca:" + rca.name
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "TIME\tALARM\tFROM\tTO")
+	for _, t := range transitions {
+		offset := t.at.Sub(since).Round(time.Millisecond)
+		_, _ = fmt.Fprintf(tw, "%+.3fs\t%s\t%s\t%s\n", offset.Seconds(), strings.TrimSuffix(t.alarm, suffix), t.from, t.to)
+	}
+	return tw.Flush()
+}
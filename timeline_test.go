@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+func TestParseHistoryItem(t *testing.T) {
+	at := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	item := &cloudwatch.AlarmHistoryItem{
+		AlarmName:       aws.String("cout:fa:adder0:rca:computer"),
+		HistoryItemType: aws.String(cloudwatch.HistoryItemTypeStateUpdate),
+		HistorySummary:  aws.String("Alarm updated from OK to ALARM"),
+		Timestamp:       aws.Time(at),
+		HistoryData:     aws.String(`{"version":"1.0","oldState":{"stateValue":"OK","stateReason":"x"},"newState":{"stateValue":"ALARM","stateReason":"y"}}`),
+	}
+	got, err := parseHistoryItem(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := transition{alarm: "cout:fa:adder0:rca:computer", at: at, from: "OK", to: "ALARM"}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	item.HistoryData = aws.String("not json")
+	if _, err := parseHistoryItem(item); err == nil {
+		t.Error("got no error for malformed history data")
+	}
+}
+
+func TestWriteTimeline(t *testing.T) {
+	rca := newRippleCarryAdder(nil, "computer")
+	since := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	transitions := []transition{
+		{alarm: rca.adders[1].coutName(), at: since.Add(2500 * time.Millisecond), from: "OK", to: "ALARM"},
+		{alarm: rca.adderLeftInName(0), at: since.Add(100 * time.Millisecond), from: "OK", to: "ALARM"},
+		{alarm: rca.adders[0].coutName(), at: since.Add(1200 * time.Millisecond), from: "OK", to: "ALARM"},
+		{alarm: rca.adderRightInName(0), at: since.Add(100 * time.Millisecond), from: "OK", to: "ALARM"},
+	}
+	sortTransitions(transitions)
+	var b strings.Builder
+	if err := rca.writeTimeline(&b, transitions, since); err != nil {
+		t.Fatal(err)
+	}
+	want := `TIME     ALARM           FROM  TO
++0.100s  lin0            OK    ALARM
++0.100s  rin0            OK    ALARM
++1.200s  cout:fa:adder0  OK    ALARM
++2.500s  cout:fa:adder1  OK    ALARM
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
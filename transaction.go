@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// buildTx records the alarms put while building a circuit, so that a build
+// that fails or is interrupted half-way can be rolled back instead of
+// leaving behind alarms that cost money. PutCompositeAlarm does not tell
+// creations from updates, so the alarms that exist before the build are
+// looked up first, and are not rolled back: rebuilding a working circuit
+// must not delete it.
+type buildTx struct {
+	cw *cloudwatch.CloudWatch
+
+	// The name of the circuit being built, used to tag its alarms.
+	circuit string
+
+	// If not nil, alarms it records as done are not put again, and alarms
+	// put successfully are recorded in it.
+	checkpoint *checkpoint
+
+	// The maximum number of alarms put concurrently, and the limit on the
+	// rate at which they are put (nil for no limit).
+	workers int
+	limiter *tokenBucket
+
+	mu sync.Mutex
+
+	// Names of the alarms put so far, in order. Alarms are put layer by
+	// layer, after the alarms in their rules, so this is a topological
+	// order.
+	put []string
+
+	// The ARNs of the alarms that existed before the build, other than
+	// those a resumed build put, by name. Rollback keeps them, and putting
+	// them again tags them, since CloudWatch only tags alarms on creation.
+	existing map[string]string
+}
+
+func newBuildTx(cw *cloudwatch.CloudWatch, circuit string) *buildTx {
+	return &buildTx{cw: cw, circuit: circuit, workers: 1}
+}
+
+// build puts the alarms described by specs, layer by layer, each layer with
+// up to tx.workers concurrent calls. It stops at the first error, or when ctx
+// is done, after waiting for the calls in flight. Rolling back is up to the
+// caller.
+func (tx *buildTx) build(ctx context.Context, specs []alarmSpec) error {
+	layers, err := buildLayers(specs)
+	if err != nil {
+		return err
+	}
+	if err := tx.findExisting(ctx, specs); err != nil {
+		return err
+	}
+	workers := tx.workers
+	if workers < 1 {
+		workers = 1
+	}
+	for _, layer := range layers {
+		var (
+			wg    sync.WaitGroup
+			mu    sync.Mutex
+			first error
+		)
+		sem := make(chan struct{}, workers)
+		for _, s := range layer {
+			sem <- struct{}{}
+			mu.Lock()
+			failed := first != nil
+			mu.Unlock()
+			if failed {
+				<-sem
+				break
+			}
+			wg.Add(1)
+			go func(s alarmSpec) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if err := tx.putAlarm(ctx, s); err != nil {
+					mu.Lock()
+					if first == nil {
+						first = err
+					}
+					mu.Unlock()
+				}
+			}(s)
+		}
+		wg.Wait()
+		if first != nil {
+			return first
+		}
+	}
+	return nil
+}
+
+// putAlarm puts the alarm, tagging it as part of the circuit being built,
+// unless the checkpoint says it was put already. Alarms that existed are
+// tagged after being put.
+func (tx *buildTx) putAlarm(ctx context.Context, s alarmSpec) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	tx.mu.Lock()
+	done := tx.checkpoint != nil && tx.checkpoint.done[s.name]
+	if done {
+		tx.put = append(tx.put, s.name)
+	}
+	tx.mu.Unlock()
+	if done {
+		return nil
+	}
+	tags := map[string]string{
+		tagCircuit: tx.circuit,
+		tagRole:    s.role,
+		tagVersion: version,
+	}
+	err := retryThrottled(ctx, func() error {
+		if err := tx.limiter.take(ctx); err != nil {
+			return err
+		}
+		return pca(ctx, tx.cw, s.name, s.rule, tags)
+	})
+	if err != nil {
+		return err
+	}
+	tx.mu.Lock()
+	arn, existed := tx.existing[s.name]
+	tx.mu.Unlock()
+	if existed {
+		err := retryThrottled(ctx, func() error {
+			if err := tx.limiter.take(ctx); err != nil {
+				return err
+			}
+			return tagAlarm(ctx, tx.cw, s.name, arn, tags)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.put = append(tx.put, s.name)
+	if tx.checkpoint != nil {
+		return tx.checkpoint.record(s.name)
+	}
+	return nil
+}
+
+// findExisting records which of the alarms described by specs exist already.
+// Those put by an earlier build with the same transaction, or by the build
+// being resumed, as the checkpoint records, are not counted as existing.
+func (tx *buildTx) findExisting(ctx context.Context, specs []alarmSpec) error {
+	names := make([]string, len(specs))
+	for i, s := range specs {
+		names[i] = s.name
+	}
+	arns := make(map[string]string)
+	err := describeNamed(ctx, tx.cw, names, func(a *cloudwatch.CompositeAlarm) {
+		arns[*a.AlarmName] = aws.StringValue(a.AlarmArn)
+	})
+	if err != nil {
+		return err
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	put := make(map[string]bool)
+	for _, name := range tx.put {
+		put[name] = true
+	}
+	if tx.existing == nil {
+		tx.existing = make(map[string]string)
+	}
+	for name, arn := range arns {
+		if !put[name] && (tx.checkpoint == nil || !tx.checkpoint.done[name]) {
+			tx.existing[name] = arn
+		}
+	}
+	return nil
+}
+
+// created returns the names of the alarms put so far that did not exist
+// before the build, in the order they were put.
+func (tx *buildTx) created() []string {
+	var names []string
+	for _, name := range tx.put {
+		if _, ok := tx.existing[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// rollback deletes the alarms the build created, in reverse order, so that no
+// alarm is deleted while another alarm's rule still references it. Alarms
+// that existed before the build are left alone, although their rules may
+// have been updated. It keeps going after a failed deletion, and returns the
+// first error encountered. The context should not be the one the build was
+// cancelled with.
+func (tx *buildTx) rollback(ctx context.Context) error {
+	var first error
+	created := tx.created()
+	for i := len(created) - 1; i >= 0; i-- {
+		if err := da(ctx, tx.cw, created[i]); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
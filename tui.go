@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// ANSI escape sequences for the full-screen terminal UI.
+const (
+	ansiAltScreen  = "\x1b[?1049h"
+	ansiMainScreen = "\x1b[?1049l"
+	ansiHideCursor = "\x1b[?25l"
+	ansiShowCursor = "\x1b[?25h"
+	ansiHome       = "\x1b[H"
+	ansiClearLine  = "\x1b[K"
+	ansiClearDown  = "\x1b[J"
+	ansiReverse    = "\x1b[7m"
+	ansiReset      = "\x1b[0m"
+)
+
+type tuiKey int
+
+const (
+	keyOther tuiKey = iota
+	keyLeft
+	keyRight
+	keyUp
+	keyDown
+	keyToggle
+	keyClear
+	keyQuit
+)
+
+// parseKeys turns what the terminal sends for key presses into keys. Arrow
+// keys and vi's hjkl move the cursor; space and enter toggle the bit under
+// it; c clears both registers; q and escape quit.
+func parseKeys(b []byte) []tuiKey {
+	var keys []tuiKey
+	for len(b) > 0 {
+		if bytes.HasPrefix(b, []byte("\x1b[")) && len(b) >= 3 {
+			switch b[2] {
+			case 'A':
+				keys = append(keys, keyUp)
+			case 'B':
+				keys = append(keys, keyDown)
+			case 'C':
+				keys = append(keys, keyRight)
+			case 'D':
+				keys = append(keys, keyLeft)
+			default:
+				keys = append(keys, keyOther)
+			}
+			b = b[3:]
+			continue
+		}
+		switch b[0] {
+		case 'h':
+			keys = append(keys, keyLeft)
+		case 'l':
+			keys = append(keys, keyRight)
+		case 'k':
+			keys = append(keys, keyUp)
+		case 'j', '\t':
+			keys = append(keys, keyDown)
+		case ' ', '\r', '\n':
+			keys = append(keys, keyToggle)
+		case 'c':
+			keys = append(keys, keyClear)
+		case 'q', '\x1b':
+			keys = append(keys, keyQuit)
+		default:
+			keys = append(keys, keyOther)
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// tuiState is what the terminal UI shows.
+type tuiState struct {
+	name string
+	a, b register
+
+	// The register and the bit under the cursor, 0 for A and 1 for B, and 7
+	// for the most significant bit, on the left.
+	row, bit int
+
+	sum      register
+	overflow bool
+
+	// When the outputs were last read, zero if never.
+	read time.Time
+
+	// The last error, or what's going on.
+	status string
+}
+
+// handle updates the state for a key, and returns whether the inputs need
+// setting.
+func (s *tuiState) handle(k tuiKey) (set bool) {
+	switch k {
+	case keyLeft:
+		if s.bit < 7 {
+			s.bit++
+		}
+	case keyRight:
+		if s.bit > 0 {
+			s.bit--
+		}
+	case keyUp, keyDown:
+		s.row = 1 - s.row
+	case keyToggle:
+		r := &s.a
+		if s.row == 1 {
+			r = &s.b
+		}
+		r[s.bit] = !r[s.bit]
+		return true
+	case keyClear:
+		s.a, s.b = register{}, register{}
+		return true
+	}
+	return false
+}
+
+// render draws the whole screen, from the top left corner.
+func (s *tuiState) render(w io.Writer) {
+	var b strings.Builder
+	b.WriteString(ansiHome)
+	line := func(format string, args ...interface{}) {
+		_, _ = fmt.Fprintf(&b, format, args...)
+		b.WriteString(ansiClearLine + "\r\n")
+	}
+	bits := func(r register, row int) string {
+		var cells []string
+		for i := 7; i >= 0; i-- {
+			c := "0"
+			if r[i] {
+				c = "1"
+			}
+			if row == s.row && i == s.bit {
+				c = ansiReverse + c + ansiReset
+			}
+			cells = append(cells, c)
+		}
+		return strings.Join(cells, " ")
+	}
+	line("Circuit %s", s.name)
+	line("")
+	line("  A        %s  %3d", bits(s.a, 0), fromRegister(s.a))
+	line("  B      + %s  %3d", bits(s.b, 1), fromRegister(s.b))
+	line("         -----------------")
+	if s.read.IsZero() {
+		line("  SUM      ? ? ? ? ? ? ? ?    ?")
+	} else {
+		overflow := " "
+		if s.overflow {
+			overflow = "1"
+		}
+		line("  SUM    %s %s  %3d", overflow, bits(s.sum, -1), fromRegister(s.sum))
+	}
+	line("")
+	if !s.read.IsZero() {
+		line("Outputs read at %s.", s.read.Format("15:04:05.000"))
+	} else {
+		line("")
+	}
+	line("%s", s.status)
+	line("")
+	line("←/→ or h/l: move   ↑/↓ or j/k: switch register   space: toggle bit   c: clear   q: quit")
+	b.WriteString(ansiClearDown)
+	_, _ = io.WriteString(w, b.String())
+}
+
+// stty runs stty on the terminal, returning its output.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("stty %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// runTUI operates the circuit interactively from the terminal, until the
+// user quits or ctx is done. The outputs are read every refresh interval.
+func (rca *rippleCarryAdder) runTUI(ctx context.Context, refresh time.Duration) error {
+	s := &tuiState{name: rca.name, bit: 7}
+	inputs := make([]string, 0, 16)
+	for i := 0; i < 8; i++ {
+		inputs = append(inputs, rca.adderLeftInName(i))
+	}
+	for i := 0; i < 8; i++ {
+		inputs = append(inputs, rca.adderRightInName(i))
+	}
+	states, err := describeStates(ctx, rca.cw, inputs)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < 8; i++ {
+		s.a[i] = states[i] == cloudwatch.StateValueAlarm
+		s.b[i] = states[8+i] == cloudwatch.StateValueAlarm
+	}
+
+	// Keys are read unbuffered and not echoed. Interrupts still generate
+	// signals, canceling ctx.
+	saved, err := stty("-g")
+	if err != nil {
+		return err
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = stty(saved)
+	}()
+	out := os.Stdout
+	_, _ = io.WriteString(out, ansiAltScreen+ansiHideCursor)
+	defer func() {
+		_, _ = io.WriteString(out, ansiShowCursor+ansiMainScreen)
+	}()
+
+	// The goroutine reading keys stays blocked on the terminal after
+	// returning, which is fine as the program is about to exit.
+	keys := make(chan []byte)
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- append([]byte(nil), buf[:n]...)
+		}
+	}()
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+	read := func() {
+		sum, overflow, err := rca.readOutputs(ctx)
+		if err != nil {
+			s.status = err.Error()
+			return
+		}
+		s.sum, s.overflow, s.read = sum, overflow, time.Now()
+	}
+	read()
+	for {
+		s.render(out)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			read()
+		case b, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, k := range parseKeys(b) {
+				if k == keyQuit {
+					return nil
+				}
+				if s.handle(k) {
+					s.status = fmt.Sprintf("Setting inputs to %d and %d...", fromRegister(s.a), fromRegister(s.b))
+					s.render(out)
+					if err := rca.setInputs(ctx, s.a, s.b); err != nil {
+						s.status = err.Error()
+					} else {
+						s.status = fmt.Sprintf("Inputs set to %d and %d.", fromRegister(s.a), fromRegister(s.b))
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseKeys(t *testing.T) {
+	got := parseKeys([]byte("\x1b[D\x1b[Cjk \rcqx\x1b[Z"))
+	want := []tuiKey{keyLeft, keyRight, keyDown, keyUp, keyToggle, keyToggle, keyClear, keyQuit, keyOther, keyOther}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTUIHandle(t *testing.T) {
+	s := &tuiState{bit: 7}
+	if s.handle(keyLeft) || s.bit != 7 {
+		t.Errorf("moved left of the most significant bit to %d", s.bit)
+	}
+	s.handle(keyRight)
+	s.handle(keyDown)
+	if !s.handle(keyToggle) {
+		t.Error("toggling does not set the inputs")
+	}
+	if got, want := fromRegister(s.b), uint8(64); got != want {
+		t.Errorf("got B %d, want %d", got, want)
+	}
+	s.a = toRegister(3)
+	if !s.handle(keyClear) || s.a != (register{}) || s.b != (register{}) {
+		t.Errorf("got A %v and B %v after clearing", s.a, s.b)
+	}
+}
+
+func TestTUIRender(t *testing.T) {
+	s := &tuiState{name: "c", a: toRegister(3), b: toRegister(255), row: 1, bit: 0}
+	var b strings.Builder
+	s.render(&b)
+	for _, want := range []string{
+		"  A        0 0 0 0 0 0 1 1    3",
+		"  B      + 1 1 1 1 1 1 1 " + ansiReverse + "1" + ansiReset + "  255",
+		"  SUM      ? ? ? ? ? ? ? ?    ?",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("screen lacks %q:\n%s", want, b.String())
+		}
+	}
+	s.sum, s.overflow, s.read = toRegister(2), true, time.Now()
+	b.Reset()
+	s.render(&b)
+	if want := "  SUM    1 0 0 0 0 0 0 1 0    2"; !strings.Contains(b.String(), want) {
+		t.Errorf("screen lacks %q:\n%s", want, b.String())
+	}
+}
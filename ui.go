@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dashboard serves a web page with the schematic of a circuit, where the
+// states of the alarms update as they change, and a form to set the inputs.
+type dashboard struct {
+	rca *rippleCarryAdder
+
+	// The interval between reads of the alarm states, for each page open.
+	refresh time.Duration
+
+	// The schematic, rendered once from the circuit model.
+	svg template.HTML
+
+	// The values of the Host header the dashboard answers to, i.e., the
+	// address it listens on, so that other sites can't reach it by making
+	// their names resolve to it (DNS rebinding).
+	hosts map[string]bool
+
+	// Serializes setting the inputs, so that concurrent additions don't mix
+	// their operands.
+	mu sync.Mutex
+}
+
+func newDashboard(rca *rippleCarryAdder, refresh time.Duration) (*dashboard, error) {
+	g, err := rca.graph(context.Background(), false)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	if err := g.writeSVG(&b); err != nil {
+		return nil, err
+	}
+	return &dashboard{rca: rca, refresh: refresh, svg: template.HTML(b.String())}, nil
+}
+
+func (d *dashboard) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", d.servePage)
+	mux.HandleFunc("/add", d.serveAdd)
+	mux.HandleFunc("/states", d.serveStates)
+	return d.sameOrigin(mux)
+}
+
+// dashboardHosts returns the values of the Host header that address the
+// dashboard listening on addr, as given, at the address it got.
+func dashboardHosts(addr string, listening net.Addr) map[string]bool {
+	hosts := map[string]bool{listening.String(): true}
+	_, port, err := net.SplitHostPort(listening.String())
+	if err != nil {
+		return hosts
+	}
+	names := []string{"localhost", "127.0.0.1", "::1"}
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		names = append(names, host)
+	}
+	for _, name := range names {
+		hosts[net.JoinHostPort(name, port)] = true
+	}
+	return hosts
+}
+
+// sameOrigin rejects requests for another host, and requests from pages of
+// other origins, which would otherwise be able to set the inputs of the
+// circuit from the browser of the user.
+func (d *dashboard) sameOrigin(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !d.hosts[r.Host] {
+			http.Error(w, "unknown host", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && origin != "http://"+r.Host {
+			http.Error(w, "cross-origin request", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+var dashboardPage = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>cac: {{.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+#schematic { overflow: auto; border: 1px solid #ccc; }
+#error { color: #d93025; }
+output { font-family: monospace; font-size: 150%; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<form id="add">
+<input name="a" type="number" min="0" max="255" value="0" required> +
+<input name="b" type="number" min="0" max="255" value="0" required>
+<button>Set inputs</button>
+= <output id="sum">?</output>
+<span id="error"></span>
+</form>
+<p>Fill is the state of each alarm; red wires carry ALARM.</p>
+<div id="schematic">{{.SVG}}</div>
+<script>
+const fills = {{.Fills}};
+const wires = {{.Wires}};
+const outputs = {{.Outputs}};
+const states = {};
+function show() {
+	let sum = 0, known = true;
+	outputs.forEach((name, i) => {
+		const state = states[name];
+		if (state === undefined || state === "INSUFFICIENT_DATA") known = false;
+		if (state === "ALARM") sum += 1 << i;
+	});
+	document.getElementById("sum").textContent = known ? sum : "?";
+}
+const source = new EventSource("states");
+source.onmessage = e => {
+	const changed = JSON.parse(e.data);
+	for (const [name, state] of Object.entries(changed)) {
+		states[name] = state;
+		document.querySelectorAll('[data-alarm="' + CSS.escape(name) + '"] path')
+			.forEach(p => p.setAttribute("fill", fills[state] || "white"));
+		document.querySelectorAll('[data-from="' + CSS.escape(name) + '"]')
+			.forEach(p => p.setAttribute("stroke", wires[state] || "black"));
+	}
+	show();
+};
+document.getElementById("add").onsubmit = async e => {
+	e.preventDefault();
+	const error = document.getElementById("error");
+	error.textContent = "";
+	const form = new FormData(e.target);
+	const r = await fetch("add", {
+		method: "POST",
+		headers: {"Content-Type": "application/json"},
+		body: JSON.stringify({a: Number(form.get("a")), b: Number(form.get("b"))}),
+	});
+	if (!r.ok) error.textContent = await r.text();
+};
+</script>
+</body>
+</html>
+`))
+
+func (d *dashboard) servePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	err := dashboardPage.Execute(w, struct {
+		Name    string
+		SVG     template.HTML
+		Fills   map[string]string
+		Wires   map[string]string
+		Outputs []string
+	}{
+		Name:    d.rca.name,
+		SVG:     d.svg,
+		Fills:   stateColors,
+		Wires:   svgWireColors,
+		Outputs: d.rca.outputNames(),
+	})
+	if err != nil {
+		log.Print(err)
+	}
+}
+
+// serveAdd sets the inputs of the circuit to the operands a and b posted as
+// a JSON object. Browsers don't send JSON to other origins without asking
+// first, which the dashboard doesn't answer, so other sites can't post.
+func (d *dashboard) serveAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+		http.Error(w, "want Content-Type application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+	var operands struct {
+		A, B json.Number
+	}
+	if err := json.NewDecoder(r.Body).Decode(&operands); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a, err := parseOperand(operands.A.String())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b, err := parseOperand(operands.B.String())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if verbose {
+		log.Printf("Setting inputs to %d and %d", a, b)
+	}
+	if err := d.rca.setInputs(r.Context(), toRegister(a), toRegister(b)); err != nil {
+		log.Print(err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveStates streams the states of the alarms of the circuit as
+// Server-Sent Events, each a JSON object mapping the names of the alarms
+// to their states. The first event has all the alarms, and the following
+// ones only those that changed.
+func (d *dashboard) serveStates(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	names := d.rca.alarmNames()
+	last := make(map[string]string)
+	ticker := time.NewTicker(d.refresh)
+	defer ticker.Stop()
+	for {
+		states, err := describeStates(r.Context(), d.rca.cw, names)
+		if err != nil {
+			if r.Context().Err() == nil {
+				log.Print(err)
+			}
+		} else {
+			changed := make(map[string]string)
+			for i, name := range names {
+				if last[name] != states[i] {
+					changed[name] = states[i]
+					last[name] = states[i]
+				}
+			}
+			if len(changed) > 0 {
+				data, err := json.Marshal(changed)
+				if err != nil {
+					log.Print(err)
+					return
+				}
+				_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+				flusher.Flush()
+			}
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// serveDashboard serves the dashboard of the circuit on addr until ctx is
+// done.
+func (rca *rippleCarryAdder) serveDashboard(ctx context.Context, addr string, refresh time.Duration) error {
+	d, err := newDashboard(rca, refresh)
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	d.hosts = dashboardHosts(addr, l.Addr())
+	log.Printf("Serving the dashboard of %q on http://%s/", rca.name, l.Addr())
+	srv := &http.Server{
+		Handler:     d.handler(),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	// Interrupted, as expected.
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return nil
+	}
+	return ctx.Err()
+}
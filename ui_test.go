@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDashboard returns a dashboard answering to the host of the requests
+// httptest.NewRequest makes.
+func testDashboard(t *testing.T) *dashboard {
+	d, err := newDashboard(newRippleCarryAdder(nil, "c"), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.hosts = map[string]bool{"example.com": true}
+	return d
+}
+
+func TestDashboardPage(t *testing.T) {
+	d := testDashboard(t)
+	rec := httptest.NewRecorder()
+	d.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"<title>cac: c</title>",
+		`<g transform="translate(40,40)" data-alarm="lin7:rca:c">`,
+		`const outputs = ["sout:ha:ha2:fa:adder0:rca:c",`,
+		`const fills = {"ALARM":"#f28b82",`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page lacks %q", want)
+		}
+	}
+	rec = httptest.NewRecorder()
+	d.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/other", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d for another page, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestDashboardAddInvalid(t *testing.T) {
+	d := testDashboard(t)
+	for _, test := range []struct {
+		method      string
+		contentType string
+		body        string
+		want        int
+	}{
+		{http.MethodGet, "", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "application/x-www-form-urlencoded", "a=1&b=2", http.StatusUnsupportedMediaType},
+		{http.MethodPost, "application/json", `{"a": 1, "b": 256}`, http.StatusBadRequest},
+		{http.MethodPost, "application/json", `{"a": -1, "b": 1}`, http.StatusBadRequest},
+		{http.MethodPost, "application/json", `{"a": "x", "b": 1}`, http.StatusBadRequest},
+	} {
+		r := httptest.NewRequest(test.method, "/add", strings.NewReader(test.body))
+		r.Header.Set("Content-Type", test.contentType)
+		rec := httptest.NewRecorder()
+		d.handler().ServeHTTP(rec, r)
+		if rec.Code != test.want {
+			t.Errorf("%s %s %s: got status %d, want %d", test.method, test.contentType, test.body, rec.Code, test.want)
+		}
+	}
+}
+
+func TestDashboardSameOrigin(t *testing.T) {
+	d := testDashboard(t)
+	for _, test := range []struct {
+		host   string
+		origin string
+		want   int
+	}{
+		{"example.com", "", http.StatusOK},
+		{"example.com", "http://example.com", http.StatusOK},
+		{"example.com", "http://evil.example", http.StatusForbidden},
+		{"evil.example", "", http.StatusForbidden},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = test.host
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+		rec := httptest.NewRecorder()
+		d.handler().ServeHTTP(rec, r)
+		if rec.Code != test.want {
+			t.Errorf("host %q, origin %q: got status %d, want %d", test.host, test.origin, rec.Code, test.want)
+		}
+	}
+}
+
+func TestDashboardHosts(t *testing.T) {
+	listening := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}
+	hosts := dashboardHosts("localhost:0", listening)
+	for _, host := range []string{"localhost:8080", "127.0.0.1:8080", "[::1]:8080"} {
+		if !hosts[host] {
+			t.Errorf("%s not allowed", host)
+		}
+	}
+	if hosts["evil.example:8080"] {
+		t.Error("evil.example:8080 allowed")
+	}
+}
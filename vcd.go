@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// The kinds of components in alarm names, e.g., sout:ha:ha2:fa:adder3:rca:c
+// is the sum output of half-adder ha2 of full adder adder3 of ripple-carry
+// adder c.
+var componentKinds = map[string]bool{"ha": true, "fa": true, "rca": true}
+
+// splitAlarmName splits an alarm name into the wire name and the scopes it
+// belongs to, outermost first. The outermost component name takes all that
+// follows its kind, since circuit names may contain colons. Names not
+// following the scheme are a single wire in no scope.
+func splitAlarmName(name string) (scopes []string, wire string) {
+	tokens := strings.Split(name, ":")
+	if len(tokens) < 3 || !componentKinds[tokens[1]] {
+		return nil, name
+	}
+	wire = tokens[0]
+	for i := 1; i+1 < len(tokens); i += 2 {
+		if i+3 < len(tokens) && componentKinds[tokens[i+2]] {
+			scopes = append(scopes, tokens[i+1])
+			continue
+		}
+		scopes = append(scopes, strings.Join(tokens[i+1:], ":"))
+		break
+	}
+	for i, j := 0, len(scopes)-1; i < j; i, j = i+1, j-1 {
+		scopes[i], scopes[j] = scopes[j], scopes[i]
+	}
+	return scopes, wire
+}
+
+// vcdIdentifier returns the short identifier code of the i-th signal, made of
+// printable ASCII characters from ! to ~.
+func vcdIdentifier(i int) string {
+	const first, n = '!', '~' - '!' + 1
+	var b []byte
+	for {
+		b = append(b, byte(first+i%n))
+		i = i/n - 1
+		if i < 0 {
+			return string(b)
+		}
+	}
+}
+
+func vcdValue(state string) byte {
+	switch state {
+	case cloudwatch.StateValueAlarm:
+		return '1'
+	case cloudwatch.StateValueOk:
+		return '0'
+	}
+	return 'x'
+}
+
+// vcdScope is a node of the tree of scopes, with the signals directly in it.
+type vcdScope struct {
+	name     string
+	children map[string]*vcdScope
+	signals  []vcdSignal
+}
+
+type vcdSignal struct {
+	alarm, wire, id string
+}
+
+func (s *vcdScope) child(name string) *vcdScope {
+	if s.children == nil {
+		s.children = make(map[string]*vcdScope)
+	}
+	c, ok := s.children[name]
+	if !ok {
+		c = &vcdScope{name: name}
+		s.children[name] = c
+	}
+	return c
+}
+
+func (s *vcdScope) write(w io.Writer) {
+	sort.Slice(s.signals, func(i, j int) bool { return s.signals[i].wire < s.signals[j].wire })
+	for _, sig := range s.signals {
+		_, _ = fmt.Fprintf(w, "$var wire 1 %s %s $end\n", sig.id, sig.wire)
+	}
+	var names []string
+	for name := range s.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "$scope module %s $end\n", name)
+		s.children[name].write(w)
+		_, _ = fmt.Fprintln(w, "$upscope $end")
+	}
+}
+
+// writeVCD writes a Value Change Dump of the given alarms, with a signal per
+// alarm, grouped into scopes following the naming hierarchy. The initial
+// states are those at time origin, and unknown for alarms not in initial.
+// Transitions are in milliseconds after origin, so the ones before it are
+// dropped; they must be sorted by time.
+func writeVCD(w io.Writer, alarms []string, initial map[string]string, transitions []transition, origin time.Time) error {
+	var root vcdScope
+	ids := make(map[string]string)
+	for i, alarm := range alarms {
+		scopes, wire := splitAlarmName(alarm)
+		s := &root
+		for _, name := range scopes {
+			s = s.child(name)
+		}
+		ids[alarm] = vcdIdentifier(i)
+		s.signals = append(s.signals, vcdSignal{alarm: alarm, wire: wire, id: ids[alarm]})
+	}
+	bw := bufio.NewWriter(w)
+	_, _ = fmt.Fprintf(bw, "$date %s $end\n", origin.UTC().Format(time.RFC3339))
+	_, _ = fmt.Fprintf(bw, "$version cac %s $end\n", version)
+	_, _ = fmt.Fprintln(bw, "$timescale 1ms $end")
+	root.write(bw)
+	_, _ = fmt.Fprintln(bw, "$enddefinitions $end")
+	_, _ = fmt.Fprintln(bw, "#0")
+	_, _ = fmt.Fprintln(bw, "$dumpvars")
+	for _, alarm := range alarms {
+		_, _ = fmt.Fprintf(bw, "%c%s\n", vcdValue(initial[alarm]), ids[alarm])
+	}
+	_, _ = fmt.Fprintln(bw, "$end")
+	last := int64(0)
+	for _, t := range transitions {
+		id, ok := ids[t.alarm]
+		if !ok || t.at.Before(origin) {
+			continue
+		}
+		if at := t.at.Sub(origin).Milliseconds(); at != last {
+			_, _ = fmt.Fprintf(bw, "#%d\n", at)
+			last = at
+		}
+		_, _ = fmt.Fprintf(bw, "%c%s\n", vcdValue(t.to), id)
+	}
+	// The first error writing, if any, is sticky.
+	return bw.Flush()
+}
+
+// initialStates returns the states of the alarms at time origin: for alarms
+// that transitioned before it, the state of the last such transition; for
+// those that transitioned only after it, the state of their first
+// transition; for the others, their current state. The transitions must be
+// sorted by time.
+func initialStates(alarms []string, current []string, transitions []transition, origin time.Time) map[string]string {
+	initial := make(map[string]string)
+	for i, alarm := range alarms {
+		initial[alarm] = current[i]
+	}
+	known := make(map[string]bool)
+	for _, t := range transitions {
+		if t.at.Before(origin) {
+			initial[t.alarm] = t.to
+			known[t.alarm] = true
+		} else if !known[t.alarm] {
+			initial[t.alarm] = t.from
+			known[t.alarm] = true
+		}
+	}
+	return initial
+}
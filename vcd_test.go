@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitAlarmName(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		scopes []string
+		wire   string
+	}{
+		{"sout:ha:ha2:fa:adder3:rca:computer", []string{"computer", "adder3", "ha2"}, "sout"},
+		{"cout:fa:adder7:rca:test:cafe", []string{"test:cafe", "adder7"}, "cout"},
+		{"lin0:rca:computer", []string{"computer"}, "lin0"},
+		{"cout:ha:test:cafe", []string{"test:cafe"}, "cout"},
+		{"left:cafe", nil, "left:cafe"},
+	} {
+		scopes, wire := splitAlarmName(test.name)
+		if !reflect.DeepEqual(scopes, test.scopes) || wire != test.wire {
+			t.Errorf("splitAlarmName(%q) = %q, %q, want %q, %q", test.name, scopes, wire, test.scopes, test.wire)
+		}
+	}
+}
+
+func TestVCDIdentifier(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 10000; i++ {
+		id := vcdIdentifier(i)
+		if seen[id] {
+			t.Fatalf("identifier %q repeated at %d", id, i)
+		}
+		seen[id] = true
+	}
+	if got := vcdIdentifier(0); got != "!" {
+		t.Errorf("got %q, want !", got)
+	}
+}
+
+func TestWriteVCD(t *testing.T) {
+	origin := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	alarms := []string{
+		"lin0:rca:c",
+		"cout:ha:ha1:fa:adder0:rca:c",
+		"sout:ha:ha1:fa:adder0:rca:c",
+	}
+	transitions := []transition{
+		{alarm: "lin0:rca:c", at: origin.Add(-time.Second), from: "ALARM", to: "OK"},
+		{alarm: "lin0:rca:c", at: origin.Add(100 * time.Millisecond), from: "OK", to: "ALARM"},
+		{alarm: "sout:ha:ha1:fa:adder0:rca:c", at: origin.Add(1500 * time.Millisecond), from: "OK", to: "ALARM"},
+	}
+	initial := initialStates(alarms, []string{"ALARM", "OK", "INSUFFICIENT_DATA"}, transitions, origin)
+	var b strings.Builder
+	if err := writeVCD(&b, alarms, initial, transitions, origin); err != nil {
+		t.Fatal(err)
+	}
+	want := `$date 2020-03-01T12:00:00Z $end
+$version cac devel $end
+$timescale 1ms $end
+$scope module c $end
+$var wire 1 ! lin0 $end
+$scope module adder0 $end
+$scope module ha1 $end
+$var wire 1 " cout $end
+$var wire 1 # sout $end
+$upscope $end
+$upscope $end
+$upscope $end
+$enddefinitions $end
+#0
+$dumpvars
+0!
+0"
+0#
+$end
+#100
+1!
+#1500
+1#
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+// failOnceWriter fails the first write only, as a full disk might before
+// space is freed.
+type failOnceWriter struct {
+	failed bool
+}
+
+func (w *failOnceWriter) Write(p []byte) (int, error) {
+	if !w.failed {
+		w.failed = true
+		return 0, errors.New("disk full")
+	}
+	return len(p), nil
+}
+
+func TestWriteVCDError(t *testing.T) {
+	origin := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	alarms := []string{"lin0:rca:c"}
+	var transitions []transition
+	for i := 0; i < 1000; i++ {
+		transitions = append(transitions, transition{alarm: alarms[0], at: origin.Add(time.Duration(i) * time.Millisecond), to: "ALARM"})
+	}
+	if err := writeVCD(&failOnceWriter{}, alarms, nil, transitions, origin); err == nil {
+		t.Error("got no error")
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+// alarmState is the state value of an alarm and when it last changed.
+type alarmState struct {
+	value   string
+	updated time.Time
+}
+
+// describeAlarmStates is like describeStates, but also returns when each
+// alarm last changed state.
+func describeAlarmStates(ctx context.Context, cw *cloudwatch.CloudWatch, alarmNames []string) ([]alarmState, error) {
+	m := make(map[string]alarmState)
+	var inconsistent []string
+	err := describeNamed(ctx, cw, alarmNames, func(a *cloudwatch.CompositeAlarm) {
+		if a.StateValue == nil {
+			inconsistent = append(inconsistent, *a.AlarmName)
+		}
+		m[*a.AlarmName] = alarmState{
+			value:   aws.StringValue(a.StateValue),
+			updated: aws.TimeValue(a.StateUpdatedTimestamp),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(inconsistent) > 0 {
+		return nil, &inconsistentReadError{
+			reason: fmt.Sprintf("no state for %s", strings.Join(quoteAll(inconsistent), ", ")),
+		}
+	}
+	var missing []string
+	for _, an := range alarmNames {
+		if _, ok := m[an]; !ok {
+			missing = append(missing, an)
+		}
+	}
+	if len(missing) > 0 {
+		found := make(map[string]string)
+		for name, s := range m {
+			found[name] = s.value
+		}
+		return nil, &alarmsNotFoundError{missing: missing, states: found}
+	}
+	states := make([]alarmState, len(alarmNames))
+	for i, an := range alarmNames {
+		states[i] = m[an]
+	}
+	return states, nil
+}
+
+// maxClockSkew is how far the local clock may be from that of CloudWatch,
+// whose timestamps are compared with local times.
+const maxClockSkew = time.Second
+
+// waitOptions controls how waitForOutputs polls.
+type waitOptions struct {
+	// How long to wait at most for the outputs to settle.
+	timeout time.Duration
+
+	// The interval between the first two reads, doubled after each read up
+	// to maxInterval.
+	interval    time.Duration
+	maxInterval time.Duration
+
+	// How long outputs must be seen not to change, since the inputs were set
+	// or since they last changed, to be considered settled, unless they
+	// have the wanted values. It must be well above the time a carry takes
+	// to ripple through the circuit, during which outputs may not change.
+	quiet time.Duration
+
+	// The expected state values of the outputs, if known. Outputs having
+	// them on two consecutive reads are settled without waiting for the
+	// quiet period.
+	want []string
+}
+
+var defaultWaitOptions = waitOptions{
+	timeout:     60 * time.Second,
+	interval:    250 * time.Millisecond,
+	maxInterval: 2 * time.Second,
+	quiet:       15 * time.Second,
+}
+
+// settled tells whether outputs have settled, given two consecutive reads of
+// their states, the wanted values if known, the time the inputs were set,
+// the last time the reads were seen to change (or since), and the current
+// time, all local times. Outputs are settled if they have the wanted values,
+// if known, or if they all transitioned after the inputs were set, if not,
+// or if they have not changed for the quiet period. CloudWatch only updates
+// StateUpdatedTimestamp on transitions, so an output whose value doesn't
+// change as a result of the new inputs never shows a timestamp after since;
+// the timestamps are also on the clock of CloudWatch, so they only count as
+// after since if they are by more than maxClockSkew.
+func settled(prev, cur []alarmState, want []string, since, changed, now time.Time, quiet time.Duration) bool {
+	if prev == nil || !equalStates(prev, cur) {
+		return false
+	}
+	if now.Sub(changed) >= quiet {
+		return true
+	}
+	if want != nil {
+		for i := range cur {
+			if cur[i].value != want[i] {
+				return false
+			}
+		}
+		return true
+	}
+	for i := range cur {
+		if !cur[i].updated.After(since.Add(maxClockSkew)) {
+			return false
+		}
+	}
+	return true
+}
+
+// waitForOutputs polls the states of the named output alarms, backing off,
+// until they have settled after the inputs were set at the given time, and
+// returns the settled state values.
+func waitForOutputs(ctx context.Context, cw *cloudwatch.CloudWatch, outputNames []string, since time.Time, opts waitOptions) ([]string, error) {
+	parent := ctx
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+	interval := opts.interval
+	var prev []alarmState
+	changed := since
+	for {
+		cur, err := describeAlarmStates(ctx, cw, outputNames)
+		if err != nil {
+			if ctx.Err() != nil && parent.Err() == nil {
+				return nil, notSettledError(opts.timeout)
+			}
+			return nil, err
+		}
+		now := time.Now()
+		if settled(prev, cur, opts.want, since, changed, now, opts.quiet) {
+			values := make([]string, len(cur))
+			for i, s := range cur {
+				values[i] = s.value
+			}
+			return values, nil
+		}
+		if prev != nil && !equalStates(prev, cur) {
+			changed = now
+		}
+		prev = cur
+		select {
+		case <-ctx.Done():
+			if parent.Err() == nil {
+				return nil, notSettledError(opts.timeout)
+			}
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		interval *= 2
+		if interval > opts.maxInterval {
+			interval = opts.maxInterval
+		}
+	}
+}
+
+func notSettledError(timeout time.Duration) error {
+	return &inconsistentReadError{
+		reason: fmt.Sprintf("outputs did not settle within %v", timeout),
+	}
+}
+
+// encodeStates returns the state values of alarms for the given bits.
+func encodeStates(bits ...bool) []string {
+	states := make([]string, len(bits))
+	for i, bit := range bits {
+		states[i] = cloudwatch.StateValueOk
+		if bit {
+			states[i] = cloudwatch.StateValueAlarm
+		}
+	}
+	return states
+}
+
+func equalStates(a, b []alarmState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
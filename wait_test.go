@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSettled(t *testing.T) {
+	since := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	before := since.Add(-time.Hour)
+	after := since.Add(2 * maxClockSkew)
+	quiet := 15 * time.Second
+	for _, test := range []struct {
+		name    string
+		prev    []alarmState
+		cur     []alarmState
+		want    []string
+		changed time.Time
+		now     time.Time
+		settled bool
+	}{
+		{
+			name:    "first read",
+			cur:     []alarmState{{"ALARM", after}},
+			now:     after,
+			settled: false,
+		},
+		{
+			name:    "all transitioned and stable",
+			prev:    []alarmState{{"ALARM", after}, {"OK", after}},
+			cur:     []alarmState{{"ALARM", after}, {"OK", after}},
+			now:     after,
+			settled: true,
+		},
+		{
+			name:    "transitioned within clock skew of since",
+			prev:    []alarmState{{"ALARM", since.Add(maxClockSkew / 2)}},
+			cur:     []alarmState{{"ALARM", since.Add(maxClockSkew / 2)}},
+			now:     after,
+			settled: false,
+		},
+		{
+			name:    "changed between reads",
+			prev:    []alarmState{{"OK", before}, {"OK", after}},
+			cur:     []alarmState{{"ALARM", after}, {"OK", after}},
+			now:     after.Add(time.Minute),
+			settled: false,
+		},
+		{
+			name:    "not transitioned, within quiet period",
+			prev:    []alarmState{{"OK", before}, {"ALARM", after}},
+			cur:     []alarmState{{"OK", before}, {"ALARM", after}},
+			now:     since.Add(quiet / 2),
+			settled: false,
+		},
+		{
+			name:    "not transitioned, quiet period over",
+			prev:    []alarmState{{"OK", before}, {"ALARM", after}},
+			cur:     []alarmState{{"OK", before}, {"ALARM", after}},
+			now:     since.Add(quiet),
+			settled: true,
+		},
+		{
+			name:    "changed recently, quiet period not over",
+			prev:    []alarmState{{"OK", before}, {"ALARM", after}},
+			cur:     []alarmState{{"OK", before}, {"ALARM", after}},
+			changed: since.Add(quiet / 2),
+			now:     since.Add(quiet),
+			settled: false,
+		},
+		{
+			name:    "wanted values",
+			prev:    []alarmState{{"OK", before}, {"ALARM", after}},
+			cur:     []alarmState{{"OK", before}, {"ALARM", after}},
+			want:    []string{"OK", "ALARM"},
+			now:     after,
+			settled: true,
+		},
+		{
+			name:    "all transitioned, but not to the wanted values",
+			prev:    []alarmState{{"ALARM", after}, {"ALARM", after}},
+			cur:     []alarmState{{"ALARM", after}, {"ALARM", after}},
+			want:    []string{"OK", "ALARM"},
+			now:     after,
+			settled: false,
+		},
+		{
+			name:    "not the wanted values, quiet period over",
+			prev:    []alarmState{{"ALARM", after}, {"ALARM", after}},
+			cur:     []alarmState{{"ALARM", after}, {"ALARM", after}},
+			want:    []string{"OK", "ALARM"},
+			now:     since.Add(quiet),
+			settled: true,
+		},
+	} {
+		changed := test.changed
+		if changed.IsZero() {
+			changed = since
+		}
+		if got := settled(test.prev, test.cur, test.want, since, changed, test.now, quiet); got != test.settled {
+			t.Errorf("%s: got %t, want %t", test.name, got, test.settled)
+		}
+	}
+}